@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/diagnosis/luxsuv-bookings/internal/availability"
+	"github.com/diagnosis/luxsuv-bookings/internal/config"
 	"github.com/diagnosis/luxsuv-bookings/internal/database"
+	"github.com/diagnosis/luxsuv-bookings/internal/geofence"
 	"github.com/diagnosis/luxsuv-bookings/internal/http/handlers"
 	"github.com/diagnosis/luxsuv-bookings/internal/http/handlers/guest"
 	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/jobs"
+	"github.com/diagnosis/luxsuv-bookings/internal/notify"
+	"github.com/diagnosis/luxsuv-bookings/internal/outbox"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/geocode"
 	"github.com/diagnosis/luxsuv-bookings/internal/platform/mailer"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/sms"
+	"github.com/diagnosis/luxsuv-bookings/internal/pricing"
+	"github.com/diagnosis/luxsuv-bookings/internal/reminders"
 	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -22,46 +36,108 @@ import (
 
 func main() {
 	_ = godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("config: %s environment", cfg.Environment)
+
 	ctx := context.Background()
-	pool, err := database.Connect(ctx)
+	pool, err := database.Connect(ctx, cfg.DatabaseURL)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer pool.Close()
 	log.Println("connected to database")
-	var emailSvc mailer.Service
-	if os.Getenv("SMTP_HOST") != "" {
-		host := os.Getenv("SMTP_HOST") // "localhost"
-		port := 1025
-		if v := os.Getenv("SMTP_PORT"); v != "" {
-			if p, err := strconv.Atoi(v); err == nil && p > 0 {
-				port = p
-			}
+
+	// Background workers run until the server has drained, then stop with
+	// workerCtx; the pool is closed only after they have returned.
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	startWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	if dir := cfg.JWT.KeysDir; dir != "" {
+		ks, err := auth.LoadKeySet(dir, cfg.JWT.SigningKID)
+		if err != nil {
+			log.Fatal(err)
 		}
-		from := os.Getenv("SMTP_FROM") // "dev@luxsuv.local"
-		user := os.Getenv("SMTP_USER") // leave empty for Mailpit
-		pass := os.Getenv("SMTP_PASS") // leave empty for Mailpit
-		useTLS := os.Getenv("SMTP_USE_TLS") == "1"
+		auth.Configure(ks, cfg.JWT.Issuer)
+		log.Printf("jwt: loaded %d key(s) from %s", len(ks.JWKS().Keys), dir)
+	} else {
+		ks, err := auth.NewEphemeralKeySet()
+		if err != nil {
+			log.Fatal(err)
+		}
+		auth.Configure(ks, cfg.JWT.Issuer)
+		log.Println("jwt: JWT_KEYS_DIR not set, using an ephemeral signing key (tokens die with the process)")
+	}
+
+	rates := pricing.DefaultRateCard()
+	if path := cfg.PricingRateCard; path != "" {
+		if rates, err = pricing.LoadRateCard(path); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("pricing: loaded rate card %q from %s", rates.Name, path)
+	}
+
+	var geocoder geocode.Geocoder = geocode.Nop{}
+	if path := cfg.GeocoderFile; path != "" {
+		if geocoder, err = geocode.LoadFile(path); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("geocode: using offline places file %s", path)
+	}
+
+	var serviceArea *geofence.Area
+	if path := cfg.ServiceAreaFile; path != "" {
+		if serviceArea, err = geofence.Load(path); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("geofence: loaded service area from %s", path)
+	} else {
+		log.Println("geofence: SERVICE_AREA_FILE not set, accepting rides anywhere")
+	}
 
-		emailSvc = mailer.NewSMTPMailer(host, port, from, user, pass, useTLS)
-		log.Printf("mailer: SMTP mode host=%s port=%d from=%s", host, port, from)
+	var mail mailer.Service
+	if c := cfg.SMTP; c.Host != "" {
+		mail = mailer.NewSMTPMailer(c.Host, c.Port, c.From, c.User, c.Pass, c.UseTLS)
+		log.Printf("mailer: SMTP mode host=%s port=%d from=%s", c.Host, c.Port, c.From)
 	} else {
-		// keep your MailerSend path available for later/staging
-		emailSvc = mailer.NewMailer(
-			os.Getenv("MAILERSEND_API_KEY"),
-			"LuxSuv Support",
-			os.Getenv("MAILER_FROM"),
-		)
+		mail = mailer.NewMailer(cfg.MailerSend.APIKey, "LuxSuv Support", cfg.MailerSend.From)
+	}
+
+	// Texts are only logged until a provider is configured; SMS_LOG_FILE
+	// collects them as JSON lines instead. Without a sender everything goes
+	// by email.
+	var smsSender sms.Sender
+	if cfg.SMSEnabled() {
+		smsSender = sms.NewLog(cfg.SMSLogFile)
 	}
 
+	// Everything the API sends goes through the outbox; the worker does the
+	// actual delivery, with retries.
+	outboxRepo := postgres.NewOutboxRepo(pool)
+	startWorker(outbox.NewWorker(outboxRepo, mail, smsSender).Run)
+
 	//repo and handlers
 	bookRepo := postgres.NewBookingRepo(pool)
+	notifier := notify.New(cfg.FrontendBaseURL)
+	notifier.EmailOnly = !cfg.SMSEnabled()
+	bookRepo.Mailer = notifier
 	idempotencyRepo := postgres.NewIdempotencyRepo(pool)
 	userRepo := postgres.NewUsersRepo(pool)
 	verifyRepo := postgres.NewVerifyRepo(pool)
+	refreshRepo := postgres.NewRefreshRepo(pool)
+	quoteRepo := postgres.NewQuoteRepo(pool)
+	vehicleRepo := postgres.NewVehicleRepo(pool)
 	//
-	guestBookings := guest.NewBookingsHandler(bookRepo, idempotencyRepo, userRepo)
-	guestAccess := guest.NewAccessHandler(verifyRepo, emailSvc, userRepo)
+	guestBookings := guest.NewBookingsHandler(bookRepo, idempotencyRepo, userRepo, vehicleRepo, geocoder, serviceArea, verifyRepo)
+	guestAccess := guest.NewAccessHandler(verifyRepo, userRepo, cfg)
 
 	// Rate limiting for guest access requests
 	accessRateLimit := mw.NewRateLimiter(pool, mw.RateLimitConfig{
@@ -70,8 +146,14 @@ func main() {
 		KeyFunc:  mw.GuestAccessRateLimitKeyFunc,
 	})
 
-	authH := handlers.NewAuthHandler(userRepo, verifyRepo, emailSvc, pool)
-	riderH := handlers.NewRiderBookingsHandler(bookRepo, userRepo)
+	authH := handlers.NewAuthHandler(userRepo, verifyRepo, refreshRepo, cfg, pool)
+	riderH := handlers.NewRiderBookingsHandler(bookRepo, userRepo, vehicleRepo, geocoder, serviceArea)
+	profileH := handlers.NewRiderProfileHandler(userRepo, verifyRepo, cfg)
+	adminH := handlers.NewAdminBookingsHandler(bookRepo)
+	driverH := handlers.NewDriverTripsHandler(bookRepo)
+	quotesH := handlers.NewQuotesHandler(quoteRepo, rates, geocoder)
+	availabilityH := handlers.NewAvailabilityHandler(availability.NewService(vehicleRepo, bookRepo))
+	vehiclesH := handlers.NewAdminVehiclesHandler(vehicleRepo)
 
 	//router
 	r := chi.NewRouter()
@@ -83,7 +165,7 @@ func main() {
 		middleware.Recoverer,
 		middleware.RedirectSlashes,
 		cors.Handler(cors.Options{
-			AllowedOrigins:   []string{"http://localhost:5173", "http://localhost:3000"},
+			AllowedOrigins:   cfg.CORSOrigins,
 			AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"}, // add PATCH
 			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key"},
 			ExposedHeaders:   []string{"Link"},
@@ -96,8 +178,13 @@ func main() {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte(`{"status": "ok"}`))
 	})
+	ready := handlers.NewReadiness(pool)
+	r.Get("/readyz", ready.ServeHTTP)
+	r.Get("/.well-known/jwks.json", handlers.JWKS)
 	//
 	r.Mount("/v1/guest/bookings", guestBookings.Routes())
+	r.Mount("/v1/quotes", quotesH.Routes())
+	r.Mount("/v1/availability", availabilityH.Routes())
 
 	// Mount guest access with rate limiting
 	r.Group(func(gr chi.Router) {
@@ -105,27 +192,44 @@ func main() {
 		gr.Mount("/v1/guest/access", guestAccess.Routes())
 	})
 
-	// Add email verification cleanup job (run every hour)
-	go func() {
-		ticker := time.NewTicker(time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			if deleted, err := verifyRepo.DeleteExpiredTokens(context.Background()); err != nil {
-				log.Printf("failed to cleanup expired verification tokens: %v", err)
-			} else if deleted > 0 {
-				log.Printf("cleaned up %d expired verification tokens", deleted)
-			}
-		}
-	}()
+	// Periodic jobs; each runs on one replica at a time.
+	reminderRepo := postgres.NewReminderRepo(pool)
+	reminderSched := reminders.New(reminderRepo, notifier)
+	jobRepo := postgres.NewJobRepo(pool)
+	rateLimitRepo := postgres.NewRateLimitRepo(pool)
+	runner := jobs.NewRunner(jobRepo)
+	runner.Register(jobs.Job{Name: "reminders", Every: time.Minute, Run: func(ctx context.Context) (int64, error) {
+		n, err := reminderSched.RunOnce(ctx)
+		return int64(n), err
+	}})
+	runner.Register(jobs.Job{Name: "verification_token_cleanup", Every: time.Hour, Run: verifyRepo.DeleteExpiredTokens})
+	runner.Register(jobs.Job{Name: "refresh_token_cleanup", Every: time.Hour, Run: refreshRepo.DeleteExpired})
+	runner.Register(jobs.Job{Name: "idempotency_cleanup", Every: time.Hour, Run: idempotencyRepo.CleanupExpired})
+	runner.Register(jobs.Job{Name: "outbox_purge", Every: time.Hour, Run: func(ctx context.Context) (int64, error) {
+		return outboxRepo.Purge(ctx, 30*24*time.Hour)
+	}})
+	runner.Register(jobs.Job{Name: "reminders_purge", Every: 24 * time.Hour, Run: func(ctx context.Context) (int64, error) {
+		return reminderRepo.Purge(ctx, 7*24*time.Hour)
+	}})
+	runner.Register(jobs.Job{Name: "job_runs_purge", Every: 24 * time.Hour, Run: func(ctx context.Context) (int64, error) {
+		return jobRepo.Purge(ctx, 30*24*time.Hour)
+	}})
+	runner.Register(jobs.Job{Name: "rate_limit_purge", Every: 15 * time.Minute, Run: rateLimitRepo.PurgeExpired})
+	startWorker(runner.Run)
 
 	r.Mount("/v1/auth", authH.Routes())
 	r.Group(func(gr chi.Router) {
 		gr.Use(mw.RequireJWT)
 		gr.Mount("/v1/rider/bookings", riderH.Routes())
+		gr.Mount("/v1/rider/me", profileH.Routes())
 	})
 	//r.Mount("/v1/rider/bookings", riderH.Routes())
+	r.Mount("/v1/admin/bookings", adminH.Routes())
+	r.Mount("/v1/admin/vehicles", vehiclesH.Routes())
+	r.Mount("/v1/admin/jobs", handlers.NewAdminJobsHandler(jobRepo).Routes())
+	r.Mount("/v1/driver/trips", driverH.Routes())
 
-	addr := ":" + env("PORT", "8080")
+	addr := ":" + cfg.Port
 	srv := &http.Server{
 		Addr:         addr,
 		Handler:      r,
@@ -133,12 +237,48 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
-	log.Println("starting server on " + addr)
-	log.Fatal(srv.ListenAndServe())
-}
-func env(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
+	go func() {
+		log.Println("starting server on " + addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	sig, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	<-sig.Done()
+	stop() // a second signal kills the process
+	log.Println("shutting down: draining requests")
+
+	// Fail readiness first and give load balancers time to notice before the
+	// listener closes.
+	ready.Drain()
+	time.Sleep(time.Duration(cfg.ShutdownDrainDelay))
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
 	}
-	return fallback
+
+	// Workers get their own deadline: a slow HTTP drain must not eat into the
+	// time they have to finish the message or job in hand.
+	stopWorkers()
+	drainCtx, cancelDrain := context.WithTimeout(ctx, 15*time.Second)
+	defer cancelDrain()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-drainCtx.Done():
+		// Closing the pool would pull it out from under their queries; exiting
+		// leaves Postgres to roll back whatever they had open.
+		log.Println("background workers did not stop in time; exiting without closing the database pool")
+		return
+	}
+
+	pool.Close()
+	log.Println("shutdown complete")
 }
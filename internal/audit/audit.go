@@ -0,0 +1,50 @@
+// Package audit carries who is acting on a request, and the request ID, down to
+// the repositories that record booking events.
+package audit
+
+import (
+	"context"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Actor is who made a change. UserID is nil for guests; Email is empty when a
+// guest acts through a manage token, in which case the booking's rider email
+// is recorded instead.
+type Actor struct {
+	Role   string
+	UserID *int64
+	Email  string
+}
+
+type ctxKey struct{}
+
+// WithActor returns a copy of ctx carrying a.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, ctxKey{}, a)
+}
+
+// FromClaims is the actor for an authenticated access token or guest session.
+func FromClaims(c *auth.Claims) Actor {
+	a := Actor{Role: c.Role, Email: c.Email}
+	if c.Sub != 0 {
+		sub := c.Sub
+		a.UserID = &sub
+	}
+	return a
+}
+
+// ActorFrom returns the actor stored in ctx. Requests without a session or
+// access token (guest creates, manage-token links) act as an anonymous guest.
+func ActorFrom(ctx context.Context) Actor {
+	if a, ok := ctx.Value(ctxKey{}).(Actor); ok {
+		return a
+	}
+	return Actor{Role: auth.RoleGuest}
+}
+
+// RequestID returns the ID chi's RequestID middleware assigned, or "".
+func RequestID(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
@@ -0,0 +1,87 @@
+// Package availability works out how many vehicles of each class are free over
+// a time range, from the active fleet and the bookings already holding vehicles.
+package availability
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+)
+
+const (
+	// SlotSize is the length of each reported slot; ranges are widened to whole slots.
+	SlotSize = time.Hour
+	// MaxRange is the longest range a single request may cover.
+	MaxRange = 14 * 24 * time.Hour
+)
+
+type Service struct {
+	Vehicles postgres.VehicleRepo
+	Bookings postgres.BookingRepo
+}
+
+func NewService(v postgres.VehicleRepo, b postgres.BookingRepo) *Service {
+	return &Service{Vehicles: v, Bookings: b}
+}
+
+// Slots reports availability between from and to for class, or for every class
+// with active vehicles when class is empty.
+func (s *Service) Slots(ctx context.Context, from, to time.Time, class string) ([]domain.AvailabilitySlot, error) {
+	fleet, err := s.Vehicles.ActiveCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if class != "" {
+		fleet = map[string]int{class: fleet[class]}
+	}
+	from, to = from.Truncate(SlotSize), ceil(to, SlotSize)
+
+	busy, err := s.Bookings.ListBusy(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return BuildSlots(from, to, fleet, busy), nil
+}
+
+// BuildSlots splits [from, to) into SlotSize slots and, for each class in fleet
+// (class → active vehicle count), reports the vehicles free for the whole slot.
+// Slots are ordered by start time, then class.
+func BuildSlots(from, to time.Time, fleet map[string]int, busy []domain.BusyInterval) []domain.AvailabilitySlot {
+	classes := make([]string, 0, len(fleet))
+	for c := range fleet {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+
+	byClass := make(map[string][]domain.BusyInterval, len(classes))
+	for _, b := range busy {
+		byClass[b.Class] = append(byClass[b.Class], b)
+	}
+
+	out := []domain.AvailabilitySlot{}
+	for start := from; start.Before(to); start = start.Add(SlotSize) {
+		end := start.Add(SlotSize)
+		for _, c := range classes {
+			booked := domain.PeakOverlap(byClass[c], start, end)
+			out = append(out, domain.AvailabilitySlot{
+				Start:        start,
+				End:          end,
+				VehicleClass: c,
+				Capacity:     fleet[c],
+				Booked:       booked,
+				Available:    max(fleet[c]-booked, 0),
+			})
+		}
+	}
+	return out
+}
+
+func ceil(t time.Time, d time.Duration) time.Time {
+	if tt := t.Truncate(d); !tt.Equal(t) {
+		return tt.Add(d)
+	}
+	return t
+}
@@ -0,0 +1,39 @@
+package availability_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/availability"
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+)
+
+func TestBuildSlots(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2030, 3, 12, h, m, 0, 0, time.UTC) }
+	fleet := map[string]int{"suv": 2, "sedan": 1}
+	busy := []domain.BusyInterval{
+		{Class: "suv", Start: at(10, 0), End: at(12, 0)},
+		{Class: "suv", Start: at(11, 30), End: at(13, 0)},
+		{Class: "suv", Start: at(13, 0), End: at(14, 0)}, // starts as the previous one ends
+		{Class: "sedan", Start: at(9, 0), End: at(10, 30)},
+	}
+
+	slots := availability.BuildSlots(at(10, 0), at(14, 0), fleet, busy)
+	if len(slots) != 8 {
+		t.Fatalf("got %d slots, want 8", len(slots))
+	}
+
+	want := map[string][]int{
+		"sedan": {0, 1, 1, 1},
+		"suv":   {1, 0, 1, 1},
+	}
+	for i, s := range slots {
+		hour := i / 2
+		if got := s.Available; got != want[s.VehicleClass][hour] {
+			t.Errorf("%s %s: available = %d, want %d", s.VehicleClass, s.Start.Format("15:04"), got, want[s.VehicleClass][hour])
+		}
+		if s.Booked+s.Available != s.Capacity {
+			t.Errorf("%s %s: booked %d + available %d != capacity %d", s.VehicleClass, s.Start.Format("15:04"), s.Booked, s.Available, s.Capacity)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/config"
+)
+
+func TestLoad_FileThenEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"port": "9000", "frontend_base_url": "https://app.example.com/", "shutdown_drain_delay": "5s", "smtp": {"host": "mail.example.com"}}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("ENVIRONMENT", "development")
+	t.Setenv("PORT", "9100")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://admin.example.com")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9100" {
+		t.Errorf("port = %q, want the env value", cfg.Port)
+	}
+	if cfg.FrontendBaseURL != "https://app.example.com" {
+		t.Errorf("frontend_base_url = %q", cfg.FrontendBaseURL)
+	}
+	if time.Duration(cfg.ShutdownDrainDelay) != 5*time.Second || cfg.SMTP.Host != "mail.example.com" || cfg.SMTP.Port != 1025 {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if len(cfg.CORSOrigins) != 2 || cfg.CORSOrigins[1] != "https://admin.example.com" {
+		t.Errorf("cors origins = %q", cfg.CORSOrigins)
+	}
+}
+
+func TestValidate_Production(t *testing.T) {
+	cfg := config.Default()
+	cfg.Environment = config.EnvProduction
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("production with development defaults validated")
+	}
+	for _, want := range []string{"keys_dir", "mailersend", "https", "sms_provider", "database_url", "localhost:5173"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	cfg.JWT.KeysDir = "/etc/luxsuv/keys"
+	cfg.MailerSend.APIKey = "key"
+	cfg.FrontendBaseURL = "https://app.example.com"
+	cfg.SMSProvider = config.SMSProviderNone
+	cfg.DatabaseURL = "postgres://luxsuv@db.internal:5432/luxsuv"
+	cfg.CORSOrigins = []string{"https://app.example.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate_EnvironmentRequired(t *testing.T) {
+	cfg := config.Default()
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "environment is required") {
+		t.Fatalf("validate without an environment = %v", err)
+	}
+}
+
+func TestValidate_StagingRefusesDevelopmentDefaults(t *testing.T) {
+	cfg := config.Default()
+	cfg.Environment = config.EnvStaging
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("staging with development defaults validated")
+	}
+	for _, want := range []string{"keys_dir", "database_url", "localhost:3000", "sms_provider"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	cfg.JWT.KeysDir = "/etc/luxsuv/keys"
+	cfg.DatabaseURL = "postgres://luxsuv@db.internal:5432/luxsuv"
+	cfg.CORSOrigins = []string{"https://staging.example.com"}
+	cfg.SMSProvider = config.SMSProviderNone
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type GuestAccessRequest struct {
 	Email string `json:"email"`
@@ -31,3 +34,13 @@ type GuestAccessCode struct {
 	Attempts  int
 	CreatedAt time.Time
 }
+
+var (
+	// ErrRefreshTokenInvalid covers unknown, expired and revoked refresh tokens.
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused means an already rotated refresh token was presented again;
+	// its whole family has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrEmailTaken means another account already uses the requested email.
+	ErrEmailTaken = errors.New("email already in use")
+)
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrSlotFull is returned when every vehicle of the requested class is already
+// taken for some part of the requested time.
+var ErrSlotFull = errors.New("no vehicles available at that time")
+
+// OccupyingStatuses are the statuses in which a booking holds a vehicle of its class.
+var OccupyingStatuses = []BookingStatus{BookingPending, BookingConfirmed, BookingAssigned, BookingOnTrip}
+
+// BusyInterval is the time a booking holds one vehicle of its class, [Start, End).
+type BusyInterval struct {
+	Class string
+	Start time.Time
+	End   time.Time
+}
+
+// AvailabilitySlot reports how many vehicles of a class are free for the whole slot.
+type AvailabilitySlot struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	VehicleClass string    `json:"vehicle_class"`
+	Capacity     int       `json:"capacity"`
+	Booked       int       `json:"booked"`
+	Available    int       `json:"available"`
+}
+
+// PeakOverlap returns the largest number of busy intervals in use at the same
+// moment within [start, end). Intervals are half-open, so one ending exactly
+// when another starts does not overlap it.
+func PeakOverlap(busy []BusyInterval, start, end time.Time) int {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	edges := make([]edge, 0, 2*len(busy))
+	for _, b := range busy {
+		s, e := b.Start, b.End
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		if !s.Before(e) {
+			continue
+		}
+		edges = append(edges, edge{s, 1}, edge{e, -1})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+
+	cur, peak := 0, 0
+	for _, e := range edges {
+		cur += e.delta
+		if cur > peak {
+			peak = cur
+		}
+	}
+	return peak
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"reflect"
+	"time"
+)
+
+type BookingEventType string
+
+const (
+	EventCreated       BookingEventType = "created"
+	EventUpdated       BookingEventType = "updated"
+	EventStatusChanged BookingEventType = "status_changed"
+	EventAssigned      BookingEventType = "assigned"
+	EventUnassigned    BookingEventType = "unassigned"
+)
+
+// FieldChange is one field of an update, before and after.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// BookingEvent is one entry of a booking's history.
+type BookingEvent struct {
+	ID         int64                  `json:"id"`
+	BookingID  int64                  `json:"booking_id"`
+	Type       BookingEventType       `json:"type"`
+	FromStatus *BookingStatus         `json:"from_status,omitempty"`
+	ToStatus   *BookingStatus         `json:"to_status,omitempty"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+
+	ActorRole   string `json:"actor_role"`
+	ActorUserID *int64 `json:"actor_user_id,omitempty"`
+	ActorEmail  string `json:"actor_email,omitempty"`
+	RequestID   string `json:"request_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Diff returns the fields the patch set whose value actually changed from old
+// to updated, keyed by JSON field name. duration_minutes is compared whenever
+// the ride type is patched too, since switching to per_ride clears it.
+func (p GuestPatch) Diff(old, updated Booking) map[string]FieldChange {
+	out := map[string]FieldChange{}
+	add := func(set bool, name string, from, to any) {
+		if set && !reflect.DeepEqual(from, to) {
+			out[name] = FieldChange{From: from, To: to}
+		}
+	}
+	add(p.RiderName != nil, "rider_name", old.RiderName, updated.RiderName)
+	add(p.RiderPhone != nil, "rider_phone", old.RiderPhone, updated.RiderPhone)
+	add(p.Pickup != nil, "pickup", old.Pickup, updated.Pickup)
+	add(p.Pickup != nil, "pickup_location", old.PickupLocation, updated.PickupLocation)
+	add(p.Dropoff != nil, "dropoff", old.Dropoff, updated.Dropoff)
+	add(p.Dropoff != nil, "dropoff_location", old.DropoffLocation, updated.DropoffLocation)
+	if p.ScheduledAt != nil && !old.ScheduledAt.Equal(updated.ScheduledAt) {
+		out["scheduled_at"] = FieldChange{From: old.ScheduledAt, To: updated.ScheduledAt}
+	}
+	add(p.Notes != nil, "notes", old.Notes, updated.Notes)
+	add(p.Passengers != nil, "passengers", old.Passengers, updated.Passengers)
+	add(p.Luggages != nil, "luggages", old.Luggages, updated.Luggages)
+	add(p.RideType != nil, "ride_type", old.RideType, updated.RideType)
+	add(p.DurationMinutes != nil || p.RideType != nil, "duration_minutes", old.DurationMinutes, updated.DurationMinutes)
+	add(p.VehicleClass != nil, "vehicle_class", old.VehicleClass, updated.VehicleClass)
+	return out
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTransition is matched (via errors.Is) by every TransitionError.
+var ErrInvalidTransition = errors.New("invalid booking status transition")
+
+var (
+	// ErrNotDriver is returned when assigning a booking to a user whose role is not driver.
+	ErrNotDriver = errors.New("user is not a driver")
+	// ErrDriverUnavailable is returned when the driver already has an assigned ride too close to the booking.
+	ErrDriverUnavailable = errors.New("driver already has a ride at that time")
+	// ErrBookingLocked is returned when a rider edits a booking that is already assigned or further along.
+	ErrBookingLocked = errors.New("booking can no longer be changed")
+)
+
+// EditableStatuses are the statuses in which a rider may still change trip details.
+var EditableStatuses = []BookingStatus{BookingPending, BookingConfirmed}
+
+// RemindableStatuses are the statuses in which riders get reminders before the trip.
+var RemindableStatuses = []BookingStatus{BookingConfirmed, BookingAssigned}
+
+// DriverRideWindow is how long a per-ride booking keeps its driver busy after
+// scheduled_at; hourly bookings are busy for their duration_minutes instead.
+// A driver's busy intervals must not overlap.
+const DriverRideWindow = 2 * time.Hour
+
+// TransitionError reports a status change the booking lifecycle does not allow.
+type TransitionError struct {
+	From BookingStatus
+	To   BookingStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move booking from %s to %s", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool { return target == ErrInvalidTransition }
+
+// bookingTransitions lists, for each status, the statuses a booking may move to next.
+// pending → confirmed → assigned → on_trip → completed; cancel only before on_trip.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingPending:   {BookingConfirmed, BookingCanceled},
+	BookingConfirmed: {BookingAssigned, BookingCanceled},
+	BookingAssigned:  {BookingOnTrip, BookingCanceled},
+	BookingOnTrip:    {BookingCompleted},
+	BookingCompleted: {},
+	BookingCanceled:  {},
+}
+
+// CanTransitionTo reports whether a booking in status s may move to next.
+func (s BookingStatus) CanTransitionTo(next BookingStatus) bool {
+	for _, to := range bookingTransitions[s] {
+		if to == next {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusesAllowing returns every status from which a booking may move to next.
+func StatusesAllowing(next BookingStatus) []BookingStatus {
+	var out []BookingStatus
+	for _, from := range []BookingStatus{
+		BookingPending, BookingConfirmed, BookingAssigned, BookingOnTrip, BookingCompleted, BookingCanceled,
+	} {
+		if from.CanTransitionTo(next) {
+			out = append(out, from)
+		}
+	}
+	return out
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 type BookingStatus string
 
@@ -22,6 +26,26 @@ func ParseBookingStatus(s string) (BookingStatus, bool) {
 	}
 }
 
+// Channel is how a rider is reached.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// ParseChannel accepts "email" and "sms"; empty means ChannelEmail.
+func ParseChannel(s string) (Channel, bool) {
+	switch Channel(s) {
+	case "", ChannelEmail:
+		return ChannelEmail, true
+	case ChannelSMS:
+		return ChannelSMS, true
+	default:
+		return "", false
+	}
+}
+
 type RideType string
 
 const (
@@ -29,6 +53,40 @@ const (
 	RideHourly  RideType = "hourly"
 )
 
+// Hourly bookings hold the vehicle for DurationMinutes, within these bounds.
+const (
+	MinHourlyMinutes = 60
+	MaxHourlyMinutes = 12 * 60
+)
+
+// ErrInvalidDuration is matched by every duration_minutes validation failure.
+var ErrInvalidDuration = errors.New("duration_minutes is required for hourly rides and not allowed for per_ride")
+
+// ValidateDuration checks duration_minutes against the ride type: required and
+// in range for hourly, absent for per_ride.
+func ValidateDuration(rt RideType, minutes *int) error {
+	switch rt {
+	case RideHourly:
+		if minutes == nil {
+			return ErrInvalidDuration
+		}
+		return ValidateDurationRange(*minutes)
+	case RidePerRide:
+		if minutes != nil {
+			return ErrInvalidDuration
+		}
+	}
+	return nil
+}
+
+// ValidateDurationRange checks an hourly duration against MinHourlyMinutes and MaxHourlyMinutes.
+func ValidateDurationRange(minutes int) error {
+	if minutes < MinHourlyMinutes || minutes > MaxHourlyMinutes {
+		return fmt.Errorf("%w: must be between %d and %d", ErrInvalidDuration, MinHourlyMinutes, MaxHourlyMinutes)
+	}
+	return nil
+}
+
 type Booking struct {
 	ID          int64         `json:"id"`
 	ManageToken string        `json:"manage_token"`
@@ -46,11 +104,34 @@ type Booking struct {
 	Passengers int      `json:"passengers"`
 	Luggages   int      `json:"luggages"`
 	RideType   RideType `json:"ride_type"`
+	// DurationMinutes is set for hourly rides only.
+	DurationMinutes *int   `json:"duration_minutes,omitempty"`
+	VehicleClass    string `json:"vehicle_class"`
+	// PickupLocation and DropoffLocation are set when the address was geocoded.
+	PickupLocation  *Location `json:"pickup_location,omitempty"`
+	DropoffLocation *Location `json:"dropoff_location,omitempty"`
 
-	UserID    *int64    `json:"user_id,omitempty"` // ← add this
-	DriverID  *int64    `json:"driver_id,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID      *int64     `json:"user_id,omitempty"` // ← add this
+	DriverID    *int64     `json:"driver_id,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	QuoteID     *string    `json:"quote_id,omitempty"`
+	FareCents   *int64     `json:"fare_cents,omitempty"`
+	// Locale is the language the rider's emails are written in.
+	Locale string `json:"locale"`
+	// ReminderChannel is how the rider is reminded of the trip.
+	ReminderChannel Channel   `json:"reminder_channel"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// EndsAt is when the booking releases its vehicle and driver: scheduled_at plus
+// the booked duration for hourly rides, or plus DriverRideWindow for per-ride.
+func (b Booking) EndsAt() time.Time {
+	if b.DurationMinutes != nil {
+		return b.ScheduledAt.Add(time.Duration(*b.DurationMinutes) * time.Minute)
+	}
+	return b.ScheduledAt.Add(DriverRideWindow)
 }
 
 type BookingGuestReq struct {
@@ -64,6 +145,21 @@ type BookingGuestReq struct {
 	Passengers  int       `json:"passengers"`
 	Luggages    int       `json:"luggages"`
 	RideType    RideType  `json:"ride_type"`
+	// DurationMinutes is required for hourly rides and must be empty for per_ride.
+	DurationMinutes *int `json:"duration_minutes,omitempty"`
+	// VehicleClass defaults to DefaultVehicleClass; passengers and luggages must fit it.
+	VehicleClass string `json:"vehicle_class,omitempty"`
+	// QuoteID optionally books at the fare of a quote from /v1/quotes.
+	QuoteID string `json:"quote_id,omitempty"`
+	// PickupLocation and DropoffLocation may be sent by clients that already
+	// geocoded the addresses; otherwise the server geocodes them.
+	PickupLocation  *Location `json:"pickup_location,omitempty"`
+	DropoffLocation *Location `json:"dropoff_location,omitempty"`
+	// Locale picks the language of the rider's emails; it defaults to the
+	// rider's account locale, or English for guests.
+	Locale string `json:"locale,omitempty"`
+	// ReminderChannel is "email" (the default) or "sms".
+	ReminderChannel Channel `json:"reminder_channel,omitempty"`
 }
 
 type BookingGuestRes struct {
@@ -73,22 +169,51 @@ type BookingGuestRes struct {
 	ScheduledAt time.Time `json:"scheduled_at"`
 }
 type BookingDTO struct {
-	ID          int64     `json:"id"`
-	Status      string    `json:"status"`
-	RiderName   string    `json:"rider_name"`
-	RiderEmail  string    `json:"rider_email"`
-	RiderPhone  string    `json:"rider_phone"`
-	Pickup      string    `json:"pickup"`
-	Dropoff     string    `json:"dropoff"`
-	ScheduledAt time.Time `json:"scheduled_at"`
-	Notes       string    `json:"notes"`
-	Passengers  int       `json:"passengers"`
-	Luggages    int       `json:"luggages"`
-	RideType    string    `json:"ride_type"`
-	DriverID    *int64    `json:"driver_id,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	UserID      *int64    `json:"user_id,omitempty"`
+	ID          int64      `json:"id"`
+	Status      string     `json:"status"`
+	RiderName   string     `json:"rider_name"`
+	RiderEmail  string     `json:"rider_email"`
+	RiderPhone  string     `json:"rider_phone"`
+	Pickup      string     `json:"pickup"`
+	Dropoff     string     `json:"dropoff"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	Notes       string     `json:"notes"`
+	Passengers  int        `json:"passengers"`
+	Luggages    int        `json:"luggages"`
+	RideType    string     `json:"ride_type"`
+	DriverID    *int64     `json:"driver_id,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	QuoteID     *string    `json:"quote_id,omitempty"`
+	FareCents   *int64     `json:"fare_cents,omitempty"`
+	// Locale is the language the rider's emails are written in.
+	Locale          string    `json:"locale"`
+	ReminderChannel Channel   `json:"reminder_channel"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	UserID          *int64    `json:"user_id,omitempty"`
+
+	// DurationMinutes is set for hourly rides; EndsAt is always computed.
+	DurationMinutes *int      `json:"duration_minutes,omitempty"`
+	EndsAt          time.Time `json:"ends_at"`
+	VehicleClass    string    `json:"vehicle_class"`
+	PickupLocation  *Location `json:"pickup_location,omitempty"`
+	DropoffLocation *Location `json:"dropoff_location,omitempty"`
+}
+
+// DTO is b as every booking endpoint returns it, without the manage token.
+func (b Booking) DTO() BookingDTO {
+	return BookingDTO{
+		ID: b.ID, Status: string(b.Status),
+		RiderName: b.RiderName, RiderEmail: b.RiderEmail, RiderPhone: b.RiderPhone,
+		Pickup: b.Pickup, Dropoff: b.Dropoff, ScheduledAt: b.ScheduledAt, Notes: b.Notes,
+		Passengers: b.Passengers, Luggages: b.Luggages, RideType: string(b.RideType),
+		DriverID: b.DriverID, StartedAt: b.StartedAt, CompletedAt: b.CompletedAt,
+		QuoteID: b.QuoteID, FareCents: b.FareCents, Locale: b.Locale, ReminderChannel: b.ReminderChannel,
+		CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt, UserID: b.UserID,
+		DurationMinutes: b.DurationMinutes, EndsAt: b.EndsAt(), VehicleClass: b.VehicleClass,
+		PickupLocation: b.PickupLocation, DropoffLocation: b.DropoffLocation,
+	}
 }
 
 type GuestPatch struct {
@@ -101,4 +226,54 @@ type GuestPatch struct {
 	Passengers  *int       `json:"passengers,omitempty"`
 	Luggages    *int       `json:"luggages,omitempty"`
 	RideType    *RideType  `json:"ride_type,omitempty"`
+	// DurationMinutes applies to hourly rides; switching to per_ride clears it.
+	DurationMinutes *int    `json:"duration_minutes,omitempty"`
+	VehicleClass    *string `json:"vehicle_class,omitempty"`
+	// A changed pickup or dropoff replaces its location; a location may only be
+	// sent together with its address.
+	PickupLocation  *Location `json:"pickup_location,omitempty"`
+	DropoffLocation *Location `json:"dropoff_location,omitempty"`
+}
+
+// ValidateDuration checks what a patch alone can tell about duration_minutes.
+// Whether the patched booking ends up hourly without a duration is only known
+// against the stored row, so the repository reports that as ErrInvalidDuration.
+func (p GuestPatch) ValidateDuration() error {
+	if p.DurationMinutes == nil {
+		return nil
+	}
+	if p.RideType != nil && *p.RideType == RidePerRide {
+		return ErrInvalidDuration
+	}
+	return ValidateDurationRange(*p.DurationMinutes)
+}
+
+// ChangesParty reports whether the patch touches what must fit the vehicle class.
+func (p GuestPatch) ChangesParty() bool {
+	return p.Passengers != nil || p.Luggages != nil || p.VehicleClass != nil
+}
+
+// Party returns the vehicle class, passengers and luggages b would have after the patch.
+func (p GuestPatch) Party(b Booking) (class string, passengers, luggages int) {
+	class, passengers, luggages = b.VehicleClass, b.Passengers, b.Luggages
+	if p.VehicleClass != nil {
+		class = *p.VehicleClass
+	}
+	if p.Passengers != nil {
+		passengers = *p.Passengers
+	}
+	if p.Luggages != nil {
+		luggages = *p.Luggages
+	}
+	return class, passengers, luggages
+}
+
+// BookingFilter narrows a booking search; nil/empty fields are ignored.
+type BookingFilter struct {
+	Status     *BookingStatus
+	RiderEmail string
+	UserID     *int64
+	DriverID   *int64
+	From       *time.Time // scheduled_at >= From
+	To         *time.Time // scheduled_at < To
 }
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// JobRun is one run of a periodic background job.
+type JobRun struct {
+	ID         int64      `json:"id"`
+	Job        string     `json:"job"`
+	Instance   string     `json:"instance"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Affected is what the job reports having processed, e.g. rows deleted.
+	Affected int64  `json:"affected"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrInvalidLocation is returned for a client-supplied location with out-of-range coordinates.
+	ErrInvalidLocation = errors.New("invalid location: lat must be within ±90 and lng within ±180")
+	// ErrOutsideServiceArea is returned when a pickup or dropoff is outside the area we operate in.
+	ErrOutsideServiceArea = errors.New("pickup or dropoff is outside our service area")
+	// ErrAddressNotLocated is returned when a service area is enforced but an address could not be geocoded.
+	ErrAddressNotLocated = errors.New("pickup or dropoff address could not be located")
+)
+
+// Location is a geocoded pickup or dropoff.
+type Location struct {
+	FormattedAddress string  `json:"formatted_address"`
+	Lat              float64 `json:"lat"`
+	Lng              float64 `json:"lng"`
+	PlaceID          string  `json:"place_id,omitempty"`
+}
+
+func (l Location) Validate() error {
+	if math.IsNaN(l.Lat) || math.IsNaN(l.Lng) || math.Abs(l.Lat) > 90 || math.Abs(l.Lng) > 180 {
+		return ErrInvalidLocation
+	}
+	return nil
+}
+
+const earthRadiusMiles = 3958.8
+
+// DistanceMiles returns the great-circle distance between a and b. Road
+// distance is longer; callers that price on it should treat this as a floor.
+func DistanceMiles(a, b Location) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := rad(b.Lat - a.Lat)
+	dLng := rad(b.Lng - a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Min(1, math.Sqrt(h)))
+}
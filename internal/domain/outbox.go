@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	// OutboxDead messages ran out of attempts. Their recipient, subject and
+	// last error are kept for inspection; their bodies are blanked.
+	OutboxDead OutboxStatus = "dead"
+)
+
+// OutboxMessage is a rendered email or text waiting in, or delivered from,
+// email_outbox. Texts go to ToPhone and only use Text.
+type OutboxMessage struct {
+	ID      int64   `json:"id"`
+	Channel Channel `json:"channel"`
+	ToEmail string  `json:"to_email,omitempty"`
+	ToName  string  `json:"to_name,omitempty"`
+	ToPhone string  `json:"to_phone,omitempty"`
+	Subject string  `json:"subject,omitempty"`
+	Text    string  `json:"text"`
+	HTML    string  `json:"html,omitempty"`
+
+	Status        OutboxStatus `json:"status"`
+	Attempts      int          `json:"attempts"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	LastError     string       `json:"last_error,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	SentAt        *time.Time   `json:"sent_at,omitempty"`
+}
+
+// To is the address or phone number the message is delivered to.
+func (m OutboxMessage) To() string {
+	if m.Channel == ChannelSMS {
+		return m.ToPhone
+	}
+	return m.ToEmail
+}
+
+// Recipient is who an email goes to.
+type Recipient struct {
+	Email  string
+	Name   string
+	Locale string
+}
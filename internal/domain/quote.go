@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrQuoteInvalid is returned when a booking references a quote that is unknown,
+// expired, already used, or was priced for a different trip.
+var ErrQuoteInvalid = errors.New("quote is invalid, expired or does not match the booking")
+
+// QuoteItem is one line of an itemized fare. Amounts are in minor units (cents).
+type QuoteItem struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+// Quote is a priced trip. The trip fields are the ones the price depends on;
+// a booking can only use a quote whose trip fields match its own.
+type Quote struct {
+	ID           string   `json:"id"`
+	RideType     RideType `json:"ride_type"`
+	VehicleClass string   `json:"vehicle_class"`
+
+	Pickup          string    `json:"pickup"`
+	Dropoff         string    `json:"dropoff"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+	DistanceMiles   float64   `json:"distance_miles"`
+	DurationMinutes int       `json:"duration_minutes"`
+
+	Currency   string      `json:"currency"`
+	Items      []QuoteItem `json:"items"`
+	TotalCents int64       `json:"total_cents"`
+	RateCard   string      `json:"rate_card"`
+
+	BookingID *int64    `json:"booking_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FareInvalidated reports whether a booking change, as returned by
+// GuestPatch.Diff, touches a trip field the fare was priced on, so the
+// booking's quote and fare no longer apply.
+func FareInvalidated(changes map[string]FieldChange) bool {
+	for name := range changes {
+		if slices.Contains([]string{"pickup", "dropoff", "scheduled_at", "ride_type", "duration_minutes", "vehicle_class"}, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// QuoteReq is a BookingGuestReq-shaped quote request. DistanceMiles and
+// DurationMinutes are the client's trip estimate, which the server only ever
+// raises; for hourly rides DurationMinutes is the booked time.
+type QuoteReq struct {
+	Pickup          string    `json:"pickup"`
+	Dropoff         string    `json:"dropoff"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+	Passengers      int       `json:"passengers"`
+	Luggages        int       `json:"luggages"`
+	RideType        RideType  `json:"ride_type"`
+	DistanceMiles   float64   `json:"distance_miles"`
+	DurationMinutes int       `json:"duration_minutes"`
+	// VehicleClass defaults to DefaultVehicleClass; a booking can only use
+	// the quote for the same class.
+	VehicleClass string `json:"vehicle_class,omitempty"`
+}
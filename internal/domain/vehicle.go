@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultVehicleClass is booked when a request doesn't name a class.
+const DefaultVehicleClass = "suv"
+
+var (
+	// ErrUnknownVehicleClass means no active vehicle of the requested class exists.
+	ErrUnknownVehicleClass = errors.New("no active vehicles in the requested class")
+	// ErrOverCapacity is matched by every CapacityError.
+	ErrOverCapacity = errors.New("party does not fit the vehicle class")
+	// ErrPlateTaken is returned when another vehicle is registered with the same plate.
+	ErrPlateTaken = errors.New("plate already registered")
+)
+
+type Vehicle struct {
+	ID              int64     `json:"id"`
+	Class           string    `json:"class"`
+	MakeModel       string    `json:"make_model"`
+	Plate           string    `json:"plate"`
+	Capacity        int       `json:"capacity"`
+	LuggageCapacity int       `json:"luggage_capacity"`
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type VehiclePatch struct {
+	Class           *string `json:"class,omitempty"`
+	MakeModel       *string `json:"make_model,omitempty"`
+	Plate           *string `json:"plate,omitempty"`
+	Capacity        *int    `json:"capacity,omitempty"`
+	LuggageCapacity *int    `json:"luggage_capacity,omitempty"`
+	Active          *bool   `json:"active,omitempty"`
+}
+
+// CapacityError reports a party larger than the biggest active vehicle of its class.
+type CapacityError struct {
+	Class           string
+	Capacity        int
+	LuggageCapacity int
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("vehicle class %s carries at most %d passengers and %d luggages", e.Class, e.Capacity, e.LuggageCapacity)
+}
+
+func (e *CapacityError) Is(target error) bool { return target == ErrOverCapacity }
@@ -0,0 +1,151 @@
+// Package geofence checks pickups and dropoffs against the service area, a set
+// of GeoJSON polygons loaded at startup.
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+)
+
+// Area is the union of the polygons we operate in. A nil *Area contains every point.
+type Area struct {
+	polygons []polygon
+}
+
+// polygon is an outer ring followed by its holes; positions are [lng, lat].
+type polygon [][][]float64
+
+// Load reads a GeoJSON FeatureCollection, Feature, Polygon or MultiPolygon file.
+func Load(path string) (*Area, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("geofence: read %s: %w", path, err)
+	}
+	a, err := Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("geofence: %s: %w", path, err)
+	}
+	return a, nil
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Features    []geoJSON       `json:"features"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// Parse builds an Area from GeoJSON. Geometries other than polygons are rejected.
+func Parse(raw []byte) (*Area, error) {
+	var g geoJSON
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, err
+	}
+	a := &Area{}
+	if err := a.add(g); err != nil {
+		return nil, err
+	}
+	if len(a.polygons) == 0 {
+		return nil, errors.New("no polygons found")
+	}
+	return a, nil
+}
+
+func (a *Area) add(g geoJSON) error {
+	switch g.Type {
+	case "FeatureCollection":
+		for _, f := range g.Features {
+			if err := a.add(f); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if g.Geometry == nil {
+			return errors.New("feature without geometry")
+		}
+		return a.add(*g.Geometry)
+	case "Polygon":
+		var p polygon
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return fmt.Errorf("polygon: %w", err)
+		}
+		a.polygons = append(a.polygons, p)
+	case "MultiPolygon":
+		var ps []polygon
+		if err := json.Unmarshal(g.Coordinates, &ps); err != nil {
+			return fmt.Errorf("multipolygon: %w", err)
+		}
+		a.polygons = append(a.polygons, ps...)
+	default:
+		return fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+	return nil
+}
+
+// Contains reports whether the point lies inside one of the area's polygons
+// and outside that polygon's holes.
+func (a *Area) Contains(lat, lng float64) bool {
+	if a == nil {
+		return true
+	}
+	for _, p := range a.polygons {
+		if len(p) == 0 || !inRing(p[0], lat, lng) {
+			continue
+		}
+		inHole := false
+		for _, hole := range p[1:] {
+			if inRing(hole, lat, lng) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns domain.ErrOutsideServiceArea if loc lies outside the area.
+// A nil location could not be geocoded and fails with
+// domain.ErrAddressNotLocated, since it cannot be shown to be inside. A nil
+// Area accepts everything.
+func (a *Area) Check(loc *domain.Location) error {
+	switch {
+	case a == nil:
+		return nil
+	case loc == nil:
+		return domain.ErrAddressNotLocated
+	case !a.Contains(loc.Lat, loc.Lng):
+		return domain.ErrOutsideServiceArea
+	}
+	return nil
+}
+
+// CheckTrip checks both ends of a trip.
+func (a *Area) CheckTrip(pickup, dropoff *domain.Location) error {
+	if err := a.Check(pickup); err != nil {
+		return err
+	}
+	return a.Check(dropoff)
+}
+
+// inRing is the even-odd ray casting test on a ring of [lng, lat] positions.
+func inRing(ring [][]float64, lat, lng float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if len(ring[i]) < 2 || len(ring[j]) < 2 {
+			continue
+		}
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
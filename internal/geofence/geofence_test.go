@@ -0,0 +1,68 @@
+package geofence_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/geofence"
+)
+
+// A 2x2 degree square with a 0.5 degree hole in the middle, as a Feature
+// inside a FeatureCollection.
+const area = `{
+  "type": "FeatureCollection",
+  "features": [{
+    "type": "Feature",
+    "properties": {"name": "test"},
+    "geometry": {
+      "type": "Polygon",
+      "coordinates": [
+        [[-123, 37], [-121, 37], [-121, 39], [-123, 39], [-123, 37]],
+        [[-122.25, 37.75], [-121.75, 37.75], [-121.75, 38.25], [-122.25, 38.25], [-122.25, 37.75]]
+      ]
+    }
+  }]
+}`
+
+func TestArea(t *testing.T) {
+	a, err := geofence.Parse([]byte(area))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     bool
+	}{
+		{"inside", 37.5, -122.5, true},
+		{"in hole", 38, -122, false},
+		{"outside", 40, -122, false},
+	}
+	for _, tt := range tests {
+		if got := a.Contains(tt.lat, tt.lng); got != tt.want {
+			t.Errorf("%s: Contains(%v, %v) = %v, want %v", tt.name, tt.lat, tt.lng, got, tt.want)
+		}
+	}
+
+	in := &domain.Location{Lat: 37.5, Lng: -122.5}
+	out := &domain.Location{Lat: 40, Lng: -122}
+	if err := a.CheckTrip(in, in); err != nil {
+		t.Errorf("CheckTrip(in, in) = %v, want nil", err)
+	}
+	if err := a.CheckTrip(in, nil); !errors.Is(err, domain.ErrAddressNotLocated) {
+		t.Errorf("CheckTrip(in, nil) = %v, want ErrAddressNotLocated", err)
+	}
+	if err := a.CheckTrip(in, out); !errors.Is(err, domain.ErrOutsideServiceArea) {
+		t.Errorf("CheckTrip(in, out) = %v, want ErrOutsideServiceArea", err)
+	}
+
+	var none *geofence.Area
+	if !none.Contains(40, -122) {
+		t.Error("nil area should contain every point")
+	}
+	if err := none.CheckTrip(nil, out); err != nil {
+		t.Errorf("nil area CheckTrip = %v, want nil", err)
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminBookingsHandler exposes every booking to the dispatch desk.
+type AdminBookingsHandler struct {
+	Bookings postgres.BookingRepo
+}
+
+func NewAdminBookingsHandler(b postgres.BookingRepo) *AdminBookingsHandler {
+	return &AdminBookingsHandler{Bookings: b}
+}
+
+func (h *AdminBookingsHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(mw.RequireJWT, mw.RequireRole(auth.RoleAdmin))
+
+	r.Group(func(rr chi.Router) {
+		rr.Use(mw.RequireScope(auth.ScopeBookingsReadAll))
+		rr.Get("/", h.list)
+		rr.Get("/{id}", h.getByID)
+		rr.Get("/{id}/history", h.history)
+	})
+
+	r.Group(func(rr chi.Router) {
+		rr.Use(mw.RequireScope(auth.ScopeBookingsWriteAll))
+		rr.Post("/{id}/confirm", h.confirm)
+		rr.Post("/{id}/assign", h.assign)
+		rr.Post("/{id}/unassign", h.unassign)
+		rr.Delete("/{id}", h.cancel)
+	})
+	return r
+}
+
+func (h *AdminBookingsHandler) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := 20, 0
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 100 {
+			limit = n
+		} else {
+			response.BadRequest(w, "Invalid limit parameter")
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		} else {
+			response.BadRequest(w, "Invalid offset parameter")
+			return
+		}
+	}
+
+	var f domain.BookingFilter
+	if raw := q.Get("status"); raw != "" {
+		st, ok := domain.ParseBookingStatus(raw)
+		if !ok {
+			response.WriteError(w, http.StatusBadRequest, "Invalid status. Must be one of: pending, confirmed, assigned, on_trip, completed, canceled", response.CodeInvalidInput)
+			return
+		}
+		f.Status = &st
+	}
+	f.RiderEmail = q.Get("email")
+	for _, p := range []struct {
+		name string
+		dst  **int64
+	}{{"user_id", &f.UserID}, {"driver_id", &f.DriverID}} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				response.BadRequest(w, "Invalid "+p.name+" parameter")
+				return
+			}
+			*p.dst = &n
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				response.BadRequest(w, "Invalid "+p.name+" parameter (RFC3339 expected)")
+				return
+			}
+			*p.dst = &t
+		}
+	}
+
+	bs, err := h.Bookings.Search(r.Context(), f, limit, offset)
+	if err != nil {
+		log.Printf("failed to search bookings: %v", err)
+		response.InternalError(w, "Failed to retrieve bookings")
+		return
+	}
+
+	out := make([]domain.BookingDTO, 0, len(bs))
+	for _, b := range bs {
+		out = append(out, b.DTO())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *AdminBookingsHandler) getByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
+		return
+	}
+	b, err := h.Bookings.GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get booking by ID: %v", err)
+		response.InternalError(w, "Failed to retrieve booking")
+		return
+	}
+	if b == nil {
+		response.NotFound(w, "Booking not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(b.DTO())
+}
+
+func (h *AdminBookingsHandler) history(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
+		return
+	}
+	b, err := h.Bookings.GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get booking by ID: %v", err)
+		response.InternalError(w, "Failed to retrieve booking")
+		return
+	}
+	if b == nil {
+		response.NotFound(w, "Booking not found")
+		return
+	}
+	events, err := h.Bookings.ListEvents(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to list booking events: %v", err)
+		response.InternalError(w, "Failed to retrieve booking history")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
+
+func (h *AdminBookingsHandler) confirm(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, domain.BookingConfirmed)
+}
+
+func (h *AdminBookingsHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, domain.BookingCanceled)
+}
+
+func (h *AdminBookingsHandler) setStatus(w http.ResponseWriter, r *http.Request, next domain.BookingStatus) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
+		return
+	}
+	ok, err := h.Bookings.UpdateStatus(r.Context(), id, next)
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		response.Conflict(w, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("failed to move booking %d to %s: %v", id, next, err)
+		response.InternalError(w, "Failed to update booking")
+		return
+	}
+	if !ok {
+		response.NotFound(w, "Booking not found")
+		return
+	}
+	h.writeBooking(w, r, id)
+}
+
+func (h *AdminBookingsHandler) assign(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
+		return
+	}
+	var in struct {
+		DriverID int64 `json:"driver_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.DriverID <= 0 {
+		response.BadRequest(w, "driver_id is required")
+		return
+	}
+
+	ok, err := h.Bookings.AssignDriver(r.Context(), id, in.DriverID)
+	switch {
+	case errors.Is(err, domain.ErrNotDriver):
+		response.BadRequest(w, "driver_id does not belong to a driver")
+		return
+	case errors.Is(err, domain.ErrInvalidTransition), errors.Is(err, domain.ErrDriverUnavailable):
+		response.Conflict(w, err.Error())
+		return
+	case err != nil:
+		log.Printf("failed to assign driver %d to booking %d: %v", in.DriverID, id, err)
+		response.InternalError(w, "Failed to assign driver")
+		return
+	case !ok:
+		response.NotFound(w, "Booking not found")
+		return
+	}
+
+	h.writeBooking(w, r, id)
+}
+
+func (h *AdminBookingsHandler) unassign(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
+		return
+	}
+	ok, err := h.Bookings.UnassignDriver(r.Context(), id)
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		response.Conflict(w, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("failed to unassign booking %d: %v", id, err)
+		response.InternalError(w, "Failed to unassign driver")
+		return
+	}
+	if !ok {
+		response.NotFound(w, "Booking not found")
+		return
+	}
+	h.writeBooking(w, r, id)
+}
+
+// writeBooking re-reads the booking after a change and writes it as the response.
+func (h *AdminBookingsHandler) writeBooking(w http.ResponseWriter, r *http.Request, id int64) {
+	b, err := h.Bookings.GetByID(r.Context(), id)
+	if err != nil || b == nil {
+		log.Printf("failed to reload booking %d: %v", id, err)
+		response.InternalError(w, "Failed to retrieve booking")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(b.DTO())
+}
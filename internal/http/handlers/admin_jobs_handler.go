@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminJobsHandler shows how the background jobs last ran.
+type AdminJobsHandler struct {
+	Jobs postgres.JobRepo
+}
+
+func NewAdminJobsHandler(j postgres.JobRepo) *AdminJobsHandler {
+	return &AdminJobsHandler{Jobs: j}
+}
+
+func (h *AdminJobsHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(mw.RequireJWT, mw.RequireRole(auth.RoleAdmin), mw.RequireScope(auth.ScopeJobsReadAll))
+	r.Get("/", h.list)
+	return r
+}
+
+func (h *AdminJobsHandler) list(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.Jobs.Latest(r.Context())
+	if err != nil {
+		log.Printf("failed to list job runs: %v", err)
+		response.InternalError(w, "Failed to list jobs")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(runs)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminVehiclesHandler manages the fleet inventory.
+type AdminVehiclesHandler struct {
+	Vehicles postgres.VehicleRepo
+}
+
+func NewAdminVehiclesHandler(v postgres.VehicleRepo) *AdminVehiclesHandler {
+	return &AdminVehiclesHandler{Vehicles: v}
+}
+
+func (h *AdminVehiclesHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(mw.RequireJWT, mw.RequireRole(auth.RoleAdmin))
+
+	r.Group(func(rr chi.Router) {
+		rr.Use(mw.RequireScope(auth.ScopeFleetReadAll))
+		rr.Get("/", h.list)
+		rr.Get("/{id}", h.getByID)
+	})
+
+	r.Group(func(rr chi.Router) {
+		rr.Use(mw.RequireScope(auth.ScopeFleetWriteAll))
+		rr.Post("/", h.create)
+		rr.Patch("/{id}", h.update)
+		rr.Delete("/{id}", h.delete)
+	})
+	return r
+}
+
+func (h *AdminVehiclesHandler) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := 20, 0
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 100 {
+			limit = n
+		} else {
+			response.BadRequest(w, "Invalid limit parameter")
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		} else {
+			response.BadRequest(w, "Invalid offset parameter")
+			return
+		}
+	}
+	class := strings.ToLower(utils.NormalizeString(q.Get("class")))
+	activeOnly := q.Get("active") == "true"
+
+	vs, err := h.Vehicles.List(r.Context(), class, activeOnly, limit, offset)
+	if err != nil {
+		log.Printf("failed to list vehicles: %v", err)
+		response.InternalError(w, "Failed to retrieve vehicles")
+		return
+	}
+	if vs == nil {
+		vs = []domain.Vehicle{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(vs)
+}
+
+func (h *AdminVehiclesHandler) getByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid vehicle ID")
+		return
+	}
+	v, err := h.Vehicles.GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get vehicle by ID: %v", err)
+		response.InternalError(w, "Failed to retrieve vehicle")
+		return
+	}
+	if v == nil {
+		response.NotFound(w, "Vehicle not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *AdminVehiclesHandler) create(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Class           string `json:"class"`
+		MakeModel       string `json:"make_model"`
+		Plate           string `json:"plate"`
+		Capacity        int    `json:"capacity"`
+		LuggageCapacity int    `json:"luggage_capacity"`
+		Active          *bool  `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+	v := &domain.Vehicle{
+		Class:           strings.ToLower(utils.NormalizeString(in.Class)),
+		MakeModel:       utils.NormalizeString(in.MakeModel),
+		Plate:           strings.ToUpper(utils.NormalizeString(in.Plate)),
+		Capacity:        in.Capacity,
+		LuggageCapacity: in.LuggageCapacity,
+		Active:          in.Active == nil || *in.Active,
+	}
+	if v.Class == "" || v.Plate == "" {
+		response.WriteError(w, http.StatusBadRequest, "Missing required fields: class, plate", response.CodeInvalidInput)
+		return
+	}
+	if v.Capacity < 1 || v.LuggageCapacity < 0 {
+		response.WriteError(w, http.StatusBadRequest, "capacity must be at least 1 and luggage_capacity cannot be negative", response.CodeInvalidInput)
+		return
+	}
+
+	out, err := h.Vehicles.Create(r.Context(), v)
+	if errors.Is(err, domain.ErrPlateTaken) {
+		response.Conflict(w, "A vehicle with this plate already exists")
+		return
+	}
+	if err != nil {
+		log.Printf("failed to create vehicle: %v", err)
+		response.InternalError(w, "Failed to create vehicle")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *AdminVehiclesHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid vehicle ID")
+		return
+	}
+	var in domain.VehiclePatch
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+	if in.Class != nil {
+		normalized := strings.ToLower(utils.NormalizeString(*in.Class))
+		if normalized == "" {
+			response.BadRequest(w, "class cannot be empty")
+			return
+		}
+		in.Class = &normalized
+	}
+	if in.MakeModel != nil {
+		normalized := utils.NormalizeString(*in.MakeModel)
+		in.MakeModel = &normalized
+	}
+	if in.Plate != nil {
+		normalized := strings.ToUpper(utils.NormalizeString(*in.Plate))
+		if normalized == "" {
+			response.BadRequest(w, "plate cannot be empty")
+			return
+		}
+		in.Plate = &normalized
+	}
+	if in.Capacity != nil && *in.Capacity < 1 {
+		response.WriteError(w, http.StatusBadRequest, "capacity must be at least 1", response.CodeInvalidInput)
+		return
+	}
+	if in.LuggageCapacity != nil && *in.LuggageCapacity < 0 {
+		response.WriteError(w, http.StatusBadRequest, "luggage_capacity cannot be negative", response.CodeInvalidInput)
+		return
+	}
+
+	v, err := h.Vehicles.Update(r.Context(), id, in)
+	if errors.Is(err, domain.ErrPlateTaken) {
+		response.Conflict(w, "A vehicle with this plate already exists")
+		return
+	}
+	if err != nil {
+		log.Printf("failed to update vehicle %d: %v", id, err)
+		response.InternalError(w, "Failed to update vehicle")
+		return
+	}
+	if v == nil {
+		response.NotFound(w, "Vehicle not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *AdminVehiclesHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid vehicle ID")
+		return
+	}
+	ok, err := h.Vehicles.Delete(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to delete vehicle %d: %v", id, err)
+		response.InternalError(w, "Failed to delete vehicle")
+		return
+	}
+	if !ok {
+		response.NotFound(w, "Vehicle not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,35 +1,44 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/alexedwards/argon2id"
+	"github.com/diagnosis/luxsuv-bookings/internal/config"
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
 	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
 	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/outbox"
 	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
-	"github.com/diagnosis/luxsuv-bookings/internal/platform/mailer"
 	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/templates"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
 type AuthHandler struct {
-	Users    postgres.UsersRepo
-	Verify   postgres.VerifyRepo
-	EmailSvc mailer.Service
-	pool     *pgxpool.Pool
+	Users   postgres.UsersRepo
+	Verify  postgres.VerifyRepo
+	Refresh postgres.RefreshRepo
+	Config  *config.Config
+	pool    *pgxpool.Pool
 }
 
-func NewAuthHandler(users postgres.UsersRepo, verify postgres.VerifyRepo, emailSvc mailer.Service, pool *pgxpool.Pool) *AuthHandler {
-	return &AuthHandler{Users: users, Verify: verify, EmailSvc: emailSvc, pool: pool}
+func NewAuthHandler(users postgres.UsersRepo, verify postgres.VerifyRepo, refresh postgres.RefreshRepo, cfg *config.Config, pool *pgxpool.Pool) *AuthHandler {
+	return &AuthHandler{Users: users, Verify: verify, Refresh: refresh, Config: cfg, pool: pool}
 }
 
 
@@ -37,6 +46,8 @@ func (h *AuthHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/register", h.register)
 	r.Post("/login", h.login)
+	r.Post("/refresh", h.refresh)
+	r.Post("/logout", h.logout)
 	
 	// Add rate limiting to verification endpoint
 	r.Group(func(rr chi.Router) {
@@ -55,10 +66,25 @@ func (h *AuthHandler) Routes() chi.Router {
 		})
 		rr.Use(verifyRateLimit.Middleware())
 		rr.Post("/verify-email", h.verifyEmail)
+		rr.Post("/confirm-email", h.confirmEmailChange)
 	})
 	
 	// Add resend verification endpoint
 	r.Post("/resend-verification", h.resendVerification)
+
+	// Password reset, rate limited per client IP
+	r.Group(func(rr chi.Router) {
+		resetRateLimit := mw.NewRateLimiter(h.pool, mw.RateLimitConfig{
+			Requests: 5,                // 5 attempts per window
+			Window:   15 * time.Minute, // 15 minute window
+			KeyFunc: func(r *http.Request) []string {
+				return []string{"password-reset:" + r.URL.Path + ":" + getClientIP(r)}
+			},
+		})
+		rr.Use(resetRateLimit.Middleware())
+		rr.Post("/forgot-password", h.forgotPassword)
+		rr.Post("/reset-password", h.resetPassword)
+	})
 	
 	return r
 }
@@ -69,6 +95,8 @@ func (h *AuthHandler) register(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 		Name     string `json:"name"`
 		Phone    string `json:"phone"`
+		// Locale is the language of the account's emails; Accept-Language is used when it is empty.
+		Locale string `json:"locale"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil ||
 		in.Email == "" || in.Password == "" || in.Name == "" || in.Phone == "" {
@@ -83,7 +111,10 @@ func (h *AuthHandler) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := h.Users.Create(r.Context(), email, hash, in.Name, in.Phone)
+	if in.Locale == "" {
+		in.Locale = r.Header.Get("Accept-Language")
+	}
+	u, err := h.Users.Create(r.Context(), email, hash, in.Name, in.Phone, templates.Locale(in.Locale))
 	if err != nil {
 		// likely duplicate email or db error
 		http.Error(w, "email exists or db error", http.StatusBadRequest)
@@ -93,51 +124,111 @@ func (h *AuthHandler) register(w http.ResponseWriter, r *http.Request) {
 	// Link historical bookings (same email)
 	_ = h.Users.LinkExistingBookings(r.Context(), u.ID, email)
 
-	// Create verification token (24h) and email it
+	// Create verification token (2h) and queue its email in the same transaction
 	vtok := uuid.NewString()
-	if err := h.Verify.CreateEmailVerification(r.Context(), u.ID, vtok, time.Now().Add(2*time.Hour)); err != nil {
-		log.Printf("failed to create email verification token: %v", err)
+	verifyURL := h.Config.FrontendBaseURL + "/verify-email?token=" + vtok
+	mail, err := outbox.Email(u.Email, u.Name, u.Locale, "verify_email",
+		templates.LinkData{Name: u.Name, URL: verifyURL, Hours: 2})
+	if err != nil {
+		log.Printf("failed to render verification email: %v", err)
 		response.InternalError(w, "Failed to create verification token")
 		return
 	}
-
-	// Get base URL from environment, fallback to localhost for development
-	baseURL := os.Getenv("FRONTEND_BASE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:5173"
-	}
-	verifyURL := baseURL + "/verify-email?token=" + vtok
-	
-	id, err := h.EmailSvc.Send(
-		u.Email, u.Name,
-		"Verify your LuxSuv account",
-		"Click to verify: "+verifyURL,
-		fmt.Sprintf(`<p>Hi %s,</p><p>Please <a href="%s">verify your email</a>. Link expires in 24 hours.</p>`, u.Name, verifyURL),
-	)
-	if err != nil {
-		log.Printf("failed to send verification email to %s: %v", u.Email, err)
-		// In production, you might want to fail registration if email can't be sent
-		// For now, we'll continue but notify the user
-		fmt.Println("[email] send failed:", err)
-		fmt.Println("[email] DEV verify URL:", verifyURL)
-	} else {
-		fmt.Println("[email] sent, id:", id)
+	if err := h.Verify.CreateEmailVerification(r.Context(), u.ID, vtok, time.Now().Add(2*time.Hour), mail); err != nil {
+		log.Printf("failed to create email verification token: %v", err)
+		response.InternalError(w, "Failed to create verification token")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	response := map[string]string{
-		"message":        "verification email sent",
+		"message": "verification email sent",
 	}
-	
+
 	// Include verify URL in development mode
-	if os.Getenv("ENVIRONMENT") == "development" {
+	if h.Config.IsDevelopment() {
 		response["dev_verify_url"] = verifyURL
 	}
-	
+
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+func (h *AuthHandler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Email == "" {
+		response.BadRequest(w, "Email is required")
+		return
+	}
+	h.sendPasswordReset(r.Context(), strings.ToLower(strings.TrimSpace(in.Email)))
+
+	// Same answer whether or not the account exists
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message": "If an account exists with this email, a password reset link has been sent",
+	})
+}
+
+// sendPasswordReset issues a reset token and emails it. Failures are only logged
+// so the response never reveals whether the account exists.
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, email string) {
+	u, err := h.Users.FindByEmail(ctx, email)
+	if err != nil || u == nil {
+		return
+	}
+
+	tok := uuid.NewString()
+	resetURL := h.Config.FrontendBaseURL + "/reset-password?token=" + tok
+	mail, err := outbox.Email(u.Email, u.Name, u.Locale, "password_reset",
+		templates.LinkData{Name: u.Name, URL: resetURL, Hours: 1})
+	if err != nil {
+		log.Printf("failed to render password reset email: %v", err)
+		return
+	}
+	if err := h.Verify.CreatePasswordReset(ctx, u.ID, tok, time.Now().Add(time.Hour), mail); err != nil {
+		log.Printf("failed to create password reset token: %v", err)
+	}
+}
+
+func (h *AuthHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Token == "" || in.Password == "" {
+		response.BadRequest(w, "Token and password are required")
+		return
+	}
+	if len(in.Password) < 8 {
+		response.BadRequest(w, "Password must be at least 8 characters")
+		return
+	}
+
+	hash, err := argon2id.CreateHash(in.Password, argon2id.DefaultParams)
+	if err != nil {
+		response.InternalError(w, "Failed to hash password")
+		return
+	}
+
+	userID, err := h.Verify.ResetPassword(r.Context(), in.Token, hash)
+	if err != nil {
+		log.Printf("failed to reset password: %v", err)
+		response.InternalError(w, "Failed to update password")
+		return
+	}
+	if userID == 0 {
+		response.WriteError(w, http.StatusUnauthorized, "Invalid or expired reset token", response.CodeExpiredToken)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password has been reset",
+	})
+}
+
 // getClientIP extracts the real client IP from the request
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header first
@@ -212,6 +303,48 @@ func (h *AuthHandler) verifyEmail(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(responseData)
 }
 
+// confirmEmailChange swaps in the address a signed-in user asked for via
+// POST /v1/rider/me/email, once they follow the link sent to it.
+func (h *AuthHandler) confirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.WriteError(w, http.StatusBadRequest, "Missing confirmation token", response.CodeInvalidInput)
+		return
+	}
+
+	userID, newEmail, err := h.Verify.ConsumeEmailChange(r.Context(), token)
+	if err != nil {
+		log.Printf("failed to consume email change token: %v", err)
+		response.InternalError(w, "Failed to process confirmation")
+		return
+	}
+	if userID == 0 {
+		response.WriteError(w, http.StatusUnauthorized, "Invalid or expired confirmation token", response.CodeExpiredToken)
+		return
+	}
+
+	if err := h.Users.UpdateEmail(r.Context(), userID, newEmail); err != nil {
+		if errors.Is(err, domain.ErrEmailTaken) {
+			response.WriteError(w, http.StatusConflict, "Email is already in use", response.CodeEmailExists)
+			return
+		}
+		log.Printf("failed to update email for user %d: %v", userID, err)
+		response.InternalError(w, "Failed to update email")
+		return
+	}
+	// The link proved ownership of the new inbox
+	if err := h.Verify.MarkUserVerified(r.Context(), userID); err != nil {
+		log.Printf("failed to mark user %d verified after email change: %v", userID, err)
+	}
+	_ = h.Users.LinkExistingBookings(r.Context(), userID, newEmail)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email updated",
+		"email":   newEmail,
+	})
+}
+
 func (h *AuthHandler) resendVerification(w http.ResponseWriter, r *http.Request) {
 	var in struct {
 		Email string `json:"email"`
@@ -245,33 +378,21 @@ func (h *AuthHandler) resendVerification(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Create new verification token
+	// Create new verification token and queue its email with it
 	vtok := uuid.NewString()
-	if err := h.Verify.CreateEmailVerification(r.Context(), u.ID, vtok, time.Now().Add(2*time.Hour)); err != nil {
-		log.Printf("failed to create email verification token: %v", err)
-		response.InternalError(w, "Failed to create verification token")
-		return
-	}
-
-	// Send verification email
-	baseURL := os.Getenv("FRONTEND_BASE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:5173"
-	}
-	verifyURL := baseURL + "/verify-email?token=" + vtok
-
-	_, err = h.EmailSvc.Send(
-		u.Email, u.Name,
-		"Verify your LuxSuv account",
-		"Click to verify: "+verifyURL,
-		fmt.Sprintf(`<p>Hi %s,</p><p>Please <a href="%s">verify your email</a>. Link expires in 2 hours.</p>`, u.Name, verifyURL),
-	)
-	
+	verifyURL := h.Config.FrontendBaseURL + "/verify-email?token=" + vtok
+	mail, err := outbox.Email(u.Email, u.Name, u.Locale, "verify_email",
+		templates.LinkData{Name: u.Name, URL: verifyURL, Hours: 2})
 	if err != nil {
-		log.Printf("failed to send verification email: %v", err)
+		log.Printf("failed to render verification email: %v", err)
 		response.InternalError(w, "Failed to send verification email")
 		return
 	}
+	if err := h.Verify.CreateEmailVerification(r.Context(), u.ID, vtok, time.Now().Add(2*time.Hour), mail); err != nil {
+		log.Printf("failed to create email verification token: %v", err)
+		response.InternalError(w, "Failed to create verification token")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
@@ -283,6 +404,7 @@ func (h *AuthHandler) login(w http.ResponseWriter, r *http.Request) {
 	var in struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Device   string `json:"device"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Email == "" || in.Password == "" {
 		http.Error(w, "invalid input", http.StatusBadRequest)
@@ -316,14 +438,102 @@ func (h *AuthHandler) login(w http.ResponseWriter, r *http.Request) {
 	// Relink any old bookings
 	_ = h.Users.LinkExistingBookings(r.Context(), u.ID, email)
 
-	// Issue short-lived access token
-	access, _ := auth.NewAccessToken(u.ID, u.Email, "rider", "bookings.read:self,bookings.write:self", 15*time.Minute)
+	// Issue short-lived access token plus a refresh token for this device
+	access, err := auth.NewAccessToken(u.ID, u.Email, u.Role, auth.ScopeForRole(u.Role), accessTokenTTL)
+	if err != nil {
+		http.Error(w, "token error", http.StatusInternalServerError)
+		return
+	}
+	refresh, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		http.Error(w, "token error", http.StatusInternalServerError)
+		return
+	}
+	device := strings.TrimSpace(in.Device)
+	if device == "" {
+		device = r.UserAgent()
+	}
+	if err := h.Refresh.Create(r.Context(), u.ID, refreshHash, device, time.Now().Add(refreshTokenTTL)); err != nil {
+		log.Printf("failed to store refresh token: %v", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"access_token": access,
+		"access_token":  access,
+		"refresh_token": refresh,
+		"expires_in":    int64(accessTokenTTL.Seconds()),
 		"user": map[string]any{
 			"id": u.ID, "email": u.Email, "name": u.Name, "phone": u.Phone, "role": u.Role, "is_verified": true,
 		},
 	})
-}
\ No newline at end of file
+}
+
+type refreshIn struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// refresh exchanges a refresh token for a new access token and a new refresh
+// token; the presented one is spent.
+func (h *AuthHandler) refresh(w http.ResponseWriter, r *http.Request) {
+	var in refreshIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.RefreshToken == "" {
+		response.BadRequest(w, "refresh_token is required")
+		return
+	}
+
+	next, nextHash, err := auth.NewRefreshToken()
+	if err != nil {
+		response.InternalError(w, "Failed to create token")
+		return
+	}
+	userID, err := h.Refresh.Rotate(r.Context(), auth.HashToken(in.RefreshToken), nextHash, time.Now().Add(refreshTokenTTL))
+	switch {
+	case errors.Is(err, domain.ErrRefreshTokenReused):
+		log.Printf("refresh token reuse detected; token family revoked")
+		response.WriteError(w, http.StatusUnauthorized, "Refresh token has already been used; please log in again", response.CodeInvalidToken)
+		return
+	case errors.Is(err, domain.ErrRefreshTokenInvalid):
+		response.WriteError(w, http.StatusUnauthorized, "Invalid or expired refresh token", response.CodeInvalidToken)
+		return
+	case err != nil:
+		log.Printf("failed to rotate refresh token: %v", err)
+		response.InternalError(w, "Failed to refresh session")
+		return
+	}
+
+	u, err := h.Users.FindByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to load user %d for refresh: %v", userID, err)
+		response.InternalError(w, "Failed to refresh session")
+		return
+	}
+	access, err := auth.NewAccessToken(u.ID, u.Email, u.Role, auth.ScopeForRole(u.Role), accessTokenTTL)
+	if err != nil {
+		response.InternalError(w, "Failed to create token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token":  access,
+		"refresh_token": next,
+		"expires_in":    int64(accessTokenTTL.Seconds()),
+	})
+}
+
+// logout revokes the refresh token and every token rotated from the same login.
+func (h *AuthHandler) logout(w http.ResponseWriter, r *http.Request) {
+	var in refreshIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.RefreshToken == "" {
+		response.BadRequest(w, "refresh_token is required")
+		return
+	}
+	if err := h.Refresh.RevokeFamily(r.Context(), auth.HashToken(in.RefreshToken)); err != nil {
+		log.Printf("failed to revoke refresh token: %v", err)
+		response.InternalError(w, "Failed to log out")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
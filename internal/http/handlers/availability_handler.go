@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/availability"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// AvailabilityHandler reports free vehicles per class and time slot. It is
+// public so guests can pick a time before booking.
+type AvailabilityHandler struct {
+	Availability *availability.Service
+}
+
+func NewAvailabilityHandler(a *availability.Service) *AvailabilityHandler {
+	return &AvailabilityHandler{Availability: a}
+}
+
+func (h *AvailabilityHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.get)
+	return r
+}
+
+func (h *AvailabilityHandler) get(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, errFrom := time.Parse(time.RFC3339, q.Get("from"))
+	to, errTo := time.Parse(time.RFC3339, q.Get("to"))
+	if errFrom != nil || errTo != nil {
+		response.BadRequest(w, "from and to must be RFC3339 timestamps")
+		return
+	}
+	if !to.After(from) {
+		response.BadRequest(w, "to must be after from")
+		return
+	}
+	if to.Sub(from) > availability.MaxRange {
+		response.BadRequest(w, "Range must not exceed 14 days")
+		return
+	}
+	class := strings.ToLower(utils.NormalizeString(q.Get("class")))
+
+	slots, err := h.Availability.Slots(r.Context(), from, to, class)
+	if err != nil {
+		log.Printf("failed to compute availability: %v", err)
+		response.InternalError(w, "Failed to compute availability")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"slot_minutes": int(availability.SlotSize / time.Minute),
+		"slots":        slots,
+	})
+}
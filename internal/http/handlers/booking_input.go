@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/geofence"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/geocode"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/utils"
+)
+
+// DecodeBookingPatch reads a booking patch from the request body, normalizes
+// and validates it, and resolves changed addresses against the geocoder and
+// service area. It writes the error response and returns false when the
+// patch is unusable. Rider and guest PATCH handlers share it.
+func DecodeBookingPatch(w http.ResponseWriter, r *http.Request, geocoder geocode.Geocoder, area *geofence.Area) (domain.GuestPatch, bool) {
+	var in domain.GuestPatch
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return in, false
+	}
+
+	// Normalize string inputs
+	for _, s := range []*string{in.RiderName, in.Pickup, in.Dropoff, in.Notes} {
+		if s != nil {
+			*s = utils.NormalizeString(*s)
+		}
+	}
+	if in.RiderPhone != nil {
+		*in.RiderPhone = utils.NormalizePhone(*in.RiderPhone)
+		if !utils.IsValidPhone(*in.RiderPhone) {
+			response.WriteError(w, http.StatusBadRequest, "Invalid phone number format", response.CodeInvalidInput)
+			return in, false
+		}
+	}
+	if in.VehicleClass != nil {
+		*in.VehicleClass = strings.ToLower(utils.NormalizeString(*in.VehicleClass))
+	}
+
+	// Validate fields if provided
+	if in.ScheduledAt != nil && in.ScheduledAt.Before(time.Now()) {
+		response.WriteError(w, http.StatusBadRequest, "Scheduled time must be in the future", response.CodePastDateTime)
+		return in, false
+	}
+	if in.Passengers != nil && *in.Passengers < 1 {
+		response.WriteError(w, http.StatusBadRequest, "Number of passengers must be at least 1", response.CodeInvalidInput)
+		return in, false
+	}
+	if in.Luggages != nil && *in.Luggages < 0 {
+		response.WriteError(w, http.StatusBadRequest, "Number of luggages cannot be negative", response.CodeInvalidInput)
+		return in, false
+	}
+	if in.RideType != nil && *in.RideType != domain.RidePerRide && *in.RideType != domain.RideHourly {
+		response.WriteError(w, http.StatusBadRequest, "Ride type must be 'per_ride' or 'hourly'", response.CodeInvalidInput)
+		return in, false
+	}
+	if err := in.ValidateDuration(); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return in, false
+	}
+	if err := geocode.ResolvePatch(r.Context(), geocoder, &in, area == nil); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return in, false
+	}
+	// Only the ends the patch moves are checked; the others were checked when set.
+	for _, end := range []struct {
+		address *string
+		loc     *domain.Location
+	}{{in.Pickup, in.PickupLocation}, {in.Dropoff, in.DropoffLocation}} {
+		if end.address == nil {
+			continue
+		}
+		if err := area.Check(end.loc); err != nil {
+			WriteAreaError(w, err)
+			return in, false
+		}
+	}
+	return in, true
+}
+
+// WriteAreaError writes the response for a failed service area check.
+func WriteAreaError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrAddressNotLocated) {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return
+	}
+	response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeOutsideServiceArea)
+}
+
+// PartyFits checks passengers and luggages against the vehicle class and writes
+// the error response when they don't fit.
+func PartyFits(w http.ResponseWriter, r *http.Request, vehicles postgres.VehicleRepo, class string, passengers, luggages int) bool {
+	err := vehicles.CheckCapacity(r.Context(), class, passengers, luggages)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, domain.ErrUnknownVehicleClass), errors.Is(err, domain.ErrOverCapacity):
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+	default:
+		log.Printf("failed to check vehicle capacity: %v", err)
+		response.InternalError(w, "Failed to check vehicle capacity")
+	}
+	return false
+}
+
+// WritePatchError writes the response for the domain errors a booking update
+// can fail with and reports whether err was one of them.
+func WritePatchError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, domain.ErrInvalidDuration), errors.Is(err, domain.ErrUnknownVehicleClass):
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+	case errors.Is(err, domain.ErrSlotFull):
+		response.Conflict(w, "No vehicles of this class are available at the requested time")
+	case errors.Is(err, domain.ErrBookingLocked):
+		response.Conflict(w, "Booking can only be changed while pending or confirmed")
+	default:
+		return false
+	}
+	return true
+}
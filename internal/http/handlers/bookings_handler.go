@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
 	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
 	"github.com/go-chi/chi/v5"
 )
@@ -114,6 +116,10 @@ func (h *BookingGuestHandler) cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ok, err := h.Repo.CancelWithToken(r.Context(), id, token)
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		response.Conflict(w, "Booking cannot be canceled in its current status")
+		return
+	}
 	if err != nil {
 		http.Error(w, "error cancelling booking", http.StatusInternalServerError)
 		log.Println("error: " + err.Error())
@@ -168,13 +174,7 @@ func (h *BookingGuestHandler) list(w http.ResponseWriter, r *http.Request) {
 
 	out := make([]domain.BookingDTO, 0, len(bs))
 	for _, b := range bs {
-		out = append(out, domain.BookingDTO{
-			ID: b.ID, Status: string(b.Status),
-			RiderName: b.RiderName, RiderEmail: b.RiderEmail, RiderPhone: b.RiderPhone,
-			Pickup: b.Pickup, Dropoff: b.Dropoff, ScheduledAt: b.ScheduledAt, Notes: b.Notes,
-			Passengers: b.Passengers, Luggages: b.Luggages, RideType: string(b.RideType),
-			DriverID: b.DriverID, CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt,
-		})
+		out = append(out, b.DTO())
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(out)
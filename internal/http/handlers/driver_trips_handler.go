@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/go-chi/chi/v5"
+)
+
+// DriverTripsHandler lets a driver see and run the bookings assigned to them.
+type DriverTripsHandler struct {
+	Bookings postgres.BookingRepo
+}
+
+func NewDriverTripsHandler(b postgres.BookingRepo) *DriverTripsHandler {
+	return &DriverTripsHandler{Bookings: b}
+}
+
+func (h *DriverTripsHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(mw.RequireJWT, mw.RequireRole(auth.RoleDriver))
+
+	r.Group(func(rr chi.Router) {
+		rr.Use(mw.RequireScope(auth.ScopeTripsReadSelf))
+		rr.Get("/", h.list)
+		rr.Get("/{id}", h.getByID)
+	})
+
+	r.Group(func(rr chi.Router) {
+		rr.Use(mw.RequireScope(auth.ScopeTripsWriteSelf))
+		rr.Post("/{id}/start", h.start)
+		rr.Post("/{id}/complete", h.complete)
+	})
+	return r
+}
+
+func (h *DriverTripsHandler) list(w http.ResponseWriter, r *http.Request) {
+	claims := mw.Claims(r)
+	limit, offset := 20, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 100 {
+			limit = n
+		} else {
+			response.BadRequest(w, "Invalid limit parameter")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		} else {
+			response.BadRequest(w, "Invalid offset parameter")
+			return
+		}
+	}
+	f := domain.BookingFilter{DriverID: &claims.Sub}
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		st, ok := domain.ParseBookingStatus(raw)
+		if !ok {
+			response.WriteError(w, http.StatusBadRequest, "Invalid status. Must be one of: pending, confirmed, assigned, on_trip, completed, canceled", response.CodeInvalidInput)
+			return
+		}
+		f.Status = &st
+	}
+
+	bs, err := h.Bookings.Search(r.Context(), f, limit, offset)
+	if err != nil {
+		log.Printf("failed to list trips for driver %d: %v", claims.Sub, err)
+		response.InternalError(w, "Failed to retrieve trips")
+		return
+	}
+
+	out := make([]domain.BookingDTO, 0, len(bs))
+	for _, b := range bs {
+		out = append(out, b.DTO())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *DriverTripsHandler) getByID(w http.ResponseWriter, r *http.Request) {
+	claims := mw.Claims(r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid trip ID")
+		return
+	}
+	b, err := h.Bookings.GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get trip by ID: %v", err)
+		response.InternalError(w, "Failed to retrieve trip")
+		return
+	}
+	if b == nil || b.DriverID == nil || *b.DriverID != claims.Sub {
+		response.NotFound(w, "Trip not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(b.DTO())
+}
+
+func (h *DriverTripsHandler) start(w http.ResponseWriter, r *http.Request) {
+	h.advance(w, r, h.Bookings.StartTrip)
+}
+
+func (h *DriverTripsHandler) complete(w http.ResponseWriter, r *http.Request) {
+	h.advance(w, r, h.Bookings.CompleteTrip)
+}
+
+// advance runs a driver-scoped status change and writes the updated trip.
+func (h *DriverTripsHandler) advance(w http.ResponseWriter, r *http.Request, step func(ctx context.Context, id, driverID int64) (bool, error)) {
+	claims := mw.Claims(r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid trip ID")
+		return
+	}
+	ok, err := step(r.Context(), id, claims.Sub)
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		response.Conflict(w, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("failed to update trip %d for driver %d: %v", id, claims.Sub, err)
+		response.InternalError(w, "Failed to update trip")
+		return
+	}
+	if !ok {
+		response.NotFound(w, "Trip not found")
+		return
+	}
+	h.getByID(w, r)
+}
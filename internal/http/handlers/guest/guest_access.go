@@ -1,6 +1,7 @@
 package guest
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,10 +10,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/diagnosis/luxsuv-bookings/internal/config"
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
 	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/outbox"
 	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
-	"github.com/diagnosis/luxsuv-bookings/internal/platform/mailer"
 	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/templates"
 	"github.com/diagnosis/luxsuv-bookings/internal/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -20,13 +24,13 @@ import (
 )
 
 type AccessHandler struct {
-	Verify   postgres.VerifyRepo
-	EmailSvc mailer.Service
+	Verify    postgres.VerifyRepo
 	UsersRepo postgres.UsersRepo
+	Config    *config.Config
 }
 
-func NewAccessHandler(verify postgres.VerifyRepo, emailSvc mailer.Service, usersRepo postgres.UsersRepo) *AccessHandler {
-	return &AccessHandler{Verify: verify, EmailSvc: emailSvc, UsersRepo: usersRepo}
+func NewAccessHandler(verify postgres.VerifyRepo, usersRepo postgres.UsersRepo, cfg *config.Config) *AccessHandler {
+	return &AccessHandler{Verify: verify, UsersRepo: usersRepo, Config: cfg}
 }
 
 func (h *AccessHandler) Routes() chi.Router {
@@ -39,6 +43,12 @@ func (h *AccessHandler) Routes() chi.Router {
 
 type requestIn struct {
 	Email string `json:"email"`
+	// Locale picks the message's language; Accept-Language is used when it is empty.
+	Locale string `json:"locale"`
+	// Channel is "email" (the default) or "sms". Texts only go to a phone
+	// verified for the email (see VerifyRepo.AddGuestPhone), never to one given
+	// in the request or on a booking; without one the code is emailed.
+	Channel string `json:"channel"`
 }
 
 func (h *AccessHandler) request(w http.ResponseWriter, r *http.Request) {
@@ -59,6 +69,11 @@ func (h *AccessHandler) request(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "Invalid email format", response.CodeInvalidInput)
 		return
 	}
+	channel, ok := domain.ParseChannel(in.Channel)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "Channel must be 'email' or 'sms'", response.CodeInvalidInput)
+		return
+	}
 
 	// Check if this email belongs to a registered user
 	if user, err := h.UsersRepo.FindByEmail(r.Context(), in.Email); err == nil && user != nil {
@@ -81,24 +96,56 @@ func (h *AccessHandler) request(w http.ResponseWriter, r *http.Request) {
 		ip = net.ParseIP(strings.TrimSpace(strings.Split(xff, ",")[0]))
 	}
 
-	if err := h.Verify.CreateGuestAccess(r.Context(), in.Email, codeHash, magic, expires, ip); err != nil {
-		log.Printf("failed to create guest access: %v", err)
+	link := h.Config.FrontendBaseURL + "/guest/access?token=" + magic
+	if in.Locale == "" {
+		in.Locale = r.Header.Get("Accept-Language")
+	}
+	data := templates.GuestAccessData{Code: code, Link: link}
+	phone := ""
+	if channel == domain.ChannelSMS && h.Config.SMSEnabled() {
+		phone = h.verifiedPhone(r.Context(), in.Email)
+	}
+	var (
+		msg     domain.OutboxMessage
+		message string
+		err     error
+	)
+	if phone != "" {
+		msg, err = outbox.SMS(phone, in.Locale, "guest_access", data)
+		message = "Access code sent to your phone"
+	} else {
+		msg, err = outbox.Email(in.Email, "", in.Locale, "guest_access", data)
+		message = "Access code sent to your email"
+	}
+	if err != nil {
+		log.Printf("failed to render guest access code: %v", err)
 		response.InternalError(w, "Failed to create access code")
 		return
 	}
 
-	link := "http://localhost:5173/guest/access?token=" + magic
-	if err := h.EmailSvc.SendGuestAccess(in.Email, code, link); err != nil {
-		log.Printf("failed to send guest access email to %s: %v", in.Email, err)
-		// Don't fail the request - code was created successfully
+	// The code is only stored together with the queued message carrying it.
+	if err := h.Verify.CreateGuestAccess(r.Context(), in.Email, codeHash, magic, expires, ip, msg); err != nil {
+		log.Printf("failed to create guest access: %v", err)
+		response.InternalError(w, "Failed to create access code")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
-		"message": "Access code sent to your email",
+		"message": message,
 	})
 }
 
+// verifiedPhone is the phone verified for email, or "" if there is none.
+func (h *AccessHandler) verifiedPhone(ctx context.Context, email string) string {
+	phone, err := h.Verify.GuestPhone(ctx, email)
+	if err != nil {
+		log.Printf("failed to look up verified phone for %s: %v", email, err)
+		return ""
+	}
+	return phone
+}
+
 type verifyIn struct {
 	Email string `json:"email"`
 	Code  string `json:"code"`
@@ -2,6 +2,7 @@ package guest
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -9,31 +10,45 @@ import (
 	"time"
 
 	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/geofence"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/handlers"
 	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
 	"github.com/diagnosis/luxsuv-bookings/internal/http/middleware/guest_middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/geocode"
 	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/templates"
 	"github.com/diagnosis/luxsuv-bookings/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
 type BookingsHandler struct{
-	Repo           *postgres.BookingRepoImpl
+	Repo           postgres.BookingRepo
 	IdempotencyRepo postgres.IdempotencyRepo
 	UsersRepo      postgres.UsersRepo
+	Vehicles       postgres.VehicleRepo
+	Geocoder       geocode.Geocoder
+	ServiceArea    *geofence.Area
+	// Verify records the phones of bookings made while signed in as the
+	// rider, which guest access codes may then be texted to.
+	Verify postgres.VerifyRepo
 }
 
-func NewBookingsHandler(repo *postgres.BookingRepoImpl, idempotencyRepo postgres.IdempotencyRepo, usersRepo postgres.UsersRepo) *BookingsHandler {
+func NewBookingsHandler(repo postgres.BookingRepo, idempotencyRepo postgres.IdempotencyRepo, usersRepo postgres.UsersRepo, vehicles postgres.VehicleRepo, geocoder geocode.Geocoder, area *geofence.Area, verify postgres.VerifyRepo) *BookingsHandler {
 	return &BookingsHandler{
 		Repo:           repo,
 		IdempotencyRepo: idempotencyRepo,
 		UsersRepo:      usersRepo,
+		Vehicles:       vehicles,
+		Geocoder:       geocoder,
+		ServiceArea:    area,
+		Verify:         verify,
 	}
 }
 
 func (h *BookingsHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.Post("/", h.create)
+	r.With(guest_middleware.OptionalGuestSession).Post("/", h.create)
 
 	r.Group(func(pr chi.Router) { // list requires session
 		pr.Use(guest_middleware.RequireGuestSession)
@@ -43,6 +58,7 @@ func (h *BookingsHandler) Routes() chi.Router {
 	r.Group(func(pr chi.Router) { // optional: manage_token OR guest session
 		pr.Use(guest_middleware.OptionalGuestSession)
 		pr.Get("/{id}", h.getByID)
+		pr.Get("/{id}/history", h.history)
 		pr.Patch("/{id}", h.patch)
 		pr.Delete("/{id}", h.cancel)
 	})
@@ -91,6 +107,14 @@ func (h *BookingsHandler) create(w http.ResponseWriter, r *http.Request) {
 	in.Pickup = utils.NormalizeString(in.Pickup)
 	in.Dropoff = utils.NormalizeString(in.Dropoff)
 	in.Notes = utils.NormalizeString(in.Notes)
+	in.VehicleClass = strings.ToLower(utils.NormalizeString(in.VehicleClass))
+	if in.VehicleClass == "" {
+		in.VehicleClass = domain.DefaultVehicleClass
+	}
+	if in.Locale == "" {
+		in.Locale = r.Header.Get("Accept-Language")
+	}
+	in.Locale = templates.Locale(in.Locale)
 
 	// Validate required fields
 	if in.RiderName == "" || in.RiderEmail == "" || in.RiderPhone == "" ||
@@ -117,27 +141,63 @@ func (h *BookingsHandler) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate numeric ranges
-	if in.Passengers < 1 || in.Passengers > 8 {
-		response.WriteError(w, http.StatusBadRequest, "Number of passengers must be between 1 and 8", response.CodeInvalidInput)
+	// Validate numeric ranges; the upper bounds come from the vehicle class
+	if in.Passengers < 1 {
+		response.WriteError(w, http.StatusBadRequest, "Number of passengers must be at least 1", response.CodeInvalidInput)
 		return
 	}
-	if in.Luggages < 0 || in.Luggages > 10 {
-		response.WriteError(w, http.StatusBadRequest, "Number of luggages must be between 0 and 10", response.CodeInvalidInput)
+	if in.Luggages < 0 {
+		response.WriteError(w, http.StatusBadRequest, "Number of luggages cannot be negative", response.CodeInvalidInput)
 		return
 	}
 	if in.RideType != domain.RidePerRide && in.RideType != domain.RideHourly {
 		response.WriteError(w, http.StatusBadRequest, "Ride type must be 'per_ride' or 'hourly'", response.CodeInvalidInput)
 		return
 	}
+	if err := domain.ValidateDuration(in.RideType, in.DurationMinutes); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return
+	}
+	channel, ok := domain.ParseChannel(string(in.ReminderChannel))
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "Reminder channel must be 'email' or 'sms'", response.CodeInvalidInput)
+		return
+	}
+	in.ReminderChannel = channel
+	if !handlers.PartyFits(w, r, h.Vehicles, in.VehicleClass, in.Passengers, in.Luggages) {
+		return
+	}
+	if err := geocode.ResolveTrip(r.Context(), h.Geocoder, &in, h.ServiceArea == nil); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return
+	}
+	if err := h.ServiceArea.CheckTrip(in.PickupLocation, in.DropoffLocation); err != nil {
+		handlers.WriteAreaError(w, err)
+		return
+	}
 
 	b, err := h.Repo.CreateGuest(r.Context(), &in)
+	if errors.Is(err, domain.ErrQuoteInvalid) {
+		response.WriteError(w, http.StatusBadRequest, "Quote is expired, already used, or does not match this booking", response.CodeInvalidInput)
+		return
+	}
+	if errors.Is(err, domain.ErrSlotFull) {
+		response.Conflict(w, "No vehicles of this class are available at the requested time")
+		return
+	}
 	if err != nil {
 		log.Printf("failed to create guest booking: %v", err)
 		response.InternalError(w, "Failed to create booking")
 		return
 	}
 
+	// A session for the rider's email proves its owner gave this phone.
+	if c := guest_middleware.Claims(r); c != nil && strings.EqualFold(c.Email, b.RiderEmail) {
+		if err := h.Verify.AddGuestPhone(r.Context(), b.RiderEmail, b.RiderPhone); err != nil {
+			log.Printf("failed to record verified phone for booking %d: %v", b.ID, err)
+		}
+	}
+
 	// Store idempotency record if key was provided
 	if idempotencyKey != "" {
 		if _, err := h.IdempotencyRepo.CheckOrCreateIdempotency(r.Context(), idempotencyKey, b.ID); err != nil {
@@ -203,14 +263,7 @@ func (h *BookingsHandler) list(w http.ResponseWriter, r *http.Request) {
 	// Convert to DTOs and ensure manage_token is not included
 	out := make([]domain.BookingDTO, 0, len(bs))
 	for _, b := range bs {
-		out = append(out, domain.BookingDTO{
-			ID: b.ID, Status: string(b.Status),
-			RiderName: b.RiderName, RiderEmail: b.RiderEmail, RiderPhone: b.RiderPhone,
-			Pickup: b.Pickup, Dropoff: b.Dropoff, ScheduledAt: b.ScheduledAt, Notes: b.Notes,
-			Passengers: b.Passengers, Luggages: b.Luggages, RideType: string(b.RideType),
-			DriverID: b.DriverID, CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt,
-			// Note: manage_token is intentionally excluded from BookingDTO
-		})
+		out = append(out, b.DTO())
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(out)
@@ -271,67 +324,86 @@ func (h *BookingsHandler) getByID(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(safeBooking)
 }
 
-func (h *BookingsHandler) patch(w http.ResponseWriter, r *http.Request) {
+// history lists a booking's events, with the same access rules as getByID.
+func (h *BookingsHandler) history(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		response.BadRequest(w, "Invalid booking ID")
 		return
 	}
 
-	var in domain.GuestPatch
-
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		response.BadRequest(w, "Invalid JSON format")
-		return
-	}
-
-	// Normalize string inputs
-	if in.RiderName != nil {
-		normalized := utils.NormalizeString(*in.RiderName)
-		in.RiderName = &normalized
-	}
-	if in.RiderPhone != nil {
-		normalized := utils.NormalizePhone(*in.RiderPhone)
-		in.RiderPhone = &normalized
-		if !utils.IsValidPhone(normalized) {
-			response.WriteError(w, http.StatusBadRequest, "Invalid phone number format", response.CodeInvalidInput)
+	var b *domain.Booking
+	if tok := r.URL.Query().Get("manage_token"); tok != "" {
+		b, err = h.Repo.GetByIDWithToken(r.Context(), id, tok)
+	} else {
+		claims := guest_middleware.Claims(r)
+		if claims == nil {
+			response.Unauthorized(w, "Authentication required. Provide either manage_token or valid guest session")
 			return
 		}
+		if claims.Role != "guest" {
+			response.Forbidden(w, "Guest session required")
+			return
+		}
+		b, err = h.Repo.GetByID(r.Context(), id)
+		if b != nil && !strings.EqualFold(b.RiderEmail, claims.Email) {
+			b = nil
+		}
 	}
-	if in.Pickup != nil {
-		normalized := utils.NormalizeString(*in.Pickup)
-		in.Pickup = &normalized
-	}
-	if in.Dropoff != nil {
-		normalized := utils.NormalizeString(*in.Dropoff)
-		in.Dropoff = &normalized
-	}
-	if in.Notes != nil {
-		normalized := utils.NormalizeString(*in.Notes)
-		in.Notes = &normalized
+	if err != nil {
+		log.Printf("failed to get booking for history: %v", err)
+		response.InternalError(w, "Failed to retrieve booking history")
+		return
 	}
-
-	// Validate fields if provided
-	if in.ScheduledAt != nil && in.ScheduledAt.Before(time.Now()) {
-		response.WriteError(w, http.StatusBadRequest, "Scheduled time must be in the future", response.CodePastDateTime)
+	if b == nil {
+		response.NotFound(w, "Booking not found")
 		return
 	}
-	if in.Passengers != nil && (*in.Passengers < 1 || *in.Passengers > 8) {
-		response.WriteError(w, http.StatusBadRequest, "Number of passengers must be between 1 and 8", response.CodeInvalidInput)
+
+	events, err := h.Repo.ListEvents(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to list booking events: %v", err)
+		response.InternalError(w, "Failed to retrieve booking history")
 		return
 	}
-	if in.Luggages != nil && (*in.Luggages < 0 || *in.Luggages > 10) {
-		response.WriteError(w, http.StatusBadRequest, "Number of luggages must be between 0 and 10", response.CodeInvalidInput)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
+
+func (h *BookingsHandler) patch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
 		return
 	}
-	if in.RideType != nil && *in.RideType != domain.RidePerRide && *in.RideType != domain.RideHourly {
-		response.WriteError(w, http.StatusBadRequest, "Ride type must be 'per_ride' or 'hourly'", response.CodeInvalidInput)
+
+	in, ok := handlers.DecodeBookingPatch(w, r, h.Geocoder, h.ServiceArea)
+	if !ok {
 		return
 	}
 
 	// Check for manage_token (public access)
 	if tok := r.URL.Query().Get("manage_token"); tok != "" {
+		if in.ChangesParty() {
+			existing, err := h.Repo.GetByIDWithToken(r.Context(), id, tok)
+			if err != nil {
+				log.Printf("failed to get booking for capacity check: %v", err)
+				response.InternalError(w, "Failed to update booking")
+				return
+			}
+			if existing == nil {
+				response.NotFound(w, "Booking not found or invalid access token")
+				return
+			}
+			class, pax, bags := in.Party(*existing)
+			if !handlers.PartyFits(w, r, h.Vehicles, class, pax, bags) {
+				return
+			}
+		}
 		b, err := h.Repo.UpdateGuest(r.Context(), id, tok, in)
+		if handlers.WritePatchError(w, err) {
+			return
+		}
 		if err != nil {
 			log.Printf("failed to update guest booking: %v", err)
 			response.InternalError(w, "Failed to update booking")
@@ -369,9 +441,18 @@ func (h *BookingsHandler) patch(w http.ResponseWriter, r *http.Request) {
 		response.NotFound(w, "Booking not found")
 		return
 	}
+	if in.ChangesParty() {
+		class, pax, bags := in.Party(*existing)
+		if !handlers.PartyFits(w, r, h.Vehicles, class, pax, bags) {
+			return
+		}
+	}
 
 	// Update using manage token (we have ownership through session)
 	b, err := h.Repo.UpdateGuest(r.Context(), id, existing.ManageToken, in)
+	if handlers.WritePatchError(w, err) {
+		return
+	}
 	if err != nil {
 		log.Printf("failed to update booking via session: %v", err)
 		response.InternalError(w, "Failed to update booking")
@@ -400,6 +481,10 @@ func (h *BookingsHandler) cancel(w http.ResponseWriter, r *http.Request) {
 	// Check for manage_token (public access)
 	if tok := r.URL.Query().Get("manage_token"); tok != "" {
 		ok, err := h.Repo.CancelWithToken(r.Context(), id, tok)
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			response.Conflict(w, "Booking cannot be canceled in its current status")
+			return
+		}
 		if err != nil {
 			log.Printf("failed to cancel booking with token: %v", err)
 			response.InternalError(w, "Failed to cancel booking")
@@ -437,13 +522,17 @@ func (h *BookingsHandler) cancel(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ok, err := h.Repo.CancelWithToken(r.Context(), id, b.ManageToken)
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		response.Conflict(w, "Booking cannot be canceled in its current status")
+		return
+	}
 	if err != nil {
 		log.Printf("failed to cancel booking: %v", err)
 		response.InternalError(w, "Failed to cancel booking")
 		return
 	}
 	if !ok {
-		response.NotFound(w, "Booking not found or already canceled")
+		response.NotFound(w, "Booking not found")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
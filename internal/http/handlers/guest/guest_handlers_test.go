@@ -8,44 +8,34 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/diagnosis/luxsuv-bookings/internal/config"
 	"github.com/diagnosis/luxsuv-bookings/internal/domain"
 	"github.com/diagnosis/luxsuv-bookings/internal/http/handlers/guest"
 	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/geocode"
 	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
 )
 
 // ---------- Mocks ----------
 
-type mockMailer struct {
-	lastTo   string
-	lastCode string
-	lastLink string
-	sendErr  error
-}
-
-func (m *mockMailer) Send(toEmail, toName, subject, text, html string) (string, error) {
-	m.lastTo = toEmail
-	return "mock-id", m.sendErr
-}
-
-func (m *mockMailer) SendGuestAccess(email, code, link string) error {
-	m.lastTo = email
-	m.lastCode = code
-	m.lastLink = link
-	return m.sendErr
-}
+var codePattern = regexp.MustCompile(`\b\d{6}\b`)
 
 type mockVerifyRepo struct {
-	codes          map[string]string // email -> code
-	magicTokens    map[string]string // token -> email
-	expirations    map[string]time.Time
-	checkCodeErr   error
+	codes           map[string]string // email -> code
+	magicTokens     map[string]string // token -> email
+	expirations     map[string]time.Time
+	phones          map[string]string // email -> verified phone
+	queued          []domain.OutboxMessage
+	checkCodeErr    error
 	createAccessErr error
 }
 
@@ -54,20 +44,38 @@ func newMockVerifyRepo() *mockVerifyRepo {
 		codes:       make(map[string]string),
 		magicTokens: make(map[string]string),
 		expirations: make(map[string]time.Time),
+		phones:      make(map[string]string),
 	}
 }
 
-func (m *mockVerifyRepo) CreateGuestAccess(_ context.Context, email, codeHash, magic string, expires time.Time, _ net.IP) error {
+func (m *mockVerifyRepo) CreateGuestAccess(_ context.Context, email, codeHash, magic string, expires time.Time, _ net.IP, msg domain.OutboxMessage) error {
 	if m.createAccessErr != nil {
 		return m.createAccessErr
 	}
-	// Store raw code for simplicity in tests
 	m.codes[email] = codeHash
 	m.magicTokens[magic] = email
 	m.expirations[email] = expires
+	m.queued = append(m.queued, msg)
+	return nil
+}
+
+// lastQueued is the message queued with the latest access code.
+func (m *mockVerifyRepo) lastQueued() domain.OutboxMessage {
+	if len(m.queued) == 0 {
+		return domain.OutboxMessage{}
+	}
+	return m.queued[len(m.queued)-1]
+}
+
+func (m *mockVerifyRepo) AddGuestPhone(_ context.Context, email, phone string) error {
+	m.phones[email] = phone
 	return nil
 }
 
+func (m *mockVerifyRepo) GuestPhone(_ context.Context, email string) (string, error) {
+	return m.phones[email], nil
+}
+
 func (m *mockVerifyRepo) CheckGuestCode(_ context.Context, email, code string) (bool, error) {
 	if m.checkCodeErr != nil {
 		return false, m.checkCodeErr
@@ -79,7 +87,7 @@ func (m *mockVerifyRepo) CheckGuestCode(_ context.Context, email, code string) (
 	if time.Now().After(m.expirations[email]) {
 		return false, nil
 	}
-	return storedCode == code, nil
+	return bcrypt.CompareHashAndPassword([]byte(storedCode), []byte(code)) == nil, nil
 }
 
 func (m *mockVerifyRepo) ConsumeGuestMagic(_ context.Context, token string) (string, bool, error) {
@@ -92,11 +100,23 @@ func (m *mockVerifyRepo) ConsumeGuestMagic(_ context.Context, token string) (str
 }
 
 // Implement other interface methods as no-ops
-func (m *mockVerifyRepo) CreateEmailVerification(context.Context, int64, string, time.Time) error { return nil }
+func (m *mockVerifyRepo) CreateEmailVerification(context.Context, int64, string, time.Time, domain.OutboxMessage) error {
+	return nil
+}
 func (m *mockVerifyRepo) ConsumeEmailVerification(context.Context, string) (int64, error) { return 0, nil }
 func (m *mockVerifyRepo) MarkUserVerified(context.Context, int64) error { return nil }
 func (m *mockVerifyRepo) IsUserVerified(context.Context, int64) (bool, error) { return false, nil }
 func (m *mockVerifyRepo) DeleteExpiredTokens(context.Context) (int64, error) { return 0, nil }
+func (m *mockVerifyRepo) CreatePasswordReset(context.Context, int64, string, time.Time, domain.OutboxMessage) error {
+	return nil
+}
+func (m *mockVerifyRepo) ResetPassword(context.Context, string, string) (int64, error) { return 0, nil }
+func (m *mockVerifyRepo) CreateEmailChange(context.Context, int64, string, string, time.Time, domain.OutboxMessage) error {
+	return nil
+}
+func (m *mockVerifyRepo) ConsumeEmailChange(context.Context, string) (int64, string, error) {
+	return 0, "", nil
+}
 
 type mockBookingRepo struct {
 	nextID   int64
@@ -194,6 +214,9 @@ func (m *mockBookingRepo) UpdateGuest(_ context.Context, id int64, token string,
 	}
 	
 	booking := m.bookings[id]
+	if !slices.Contains(domain.EditableStatuses, booking.Status) {
+		return nil, domain.ErrBookingLocked
+	}
 	if patch.Notes != nil {
 		booking.Notes = *patch.Notes
 	}
@@ -212,8 +235,8 @@ func (m *mockBookingRepo) CancelWithToken(_ context.Context, id int64, token str
 	}
 	
 	booking := m.bookings[id]
-	if booking.Status == domain.BookingCanceled {
-		return false, nil
+	if !booking.Status.CanTransitionTo(domain.BookingCanceled) {
+		return false, &domain.TransitionError{From: booking.Status, To: domain.BookingCanceled}
 	}
 	
 	booking.Status = domain.BookingCanceled
@@ -226,6 +249,21 @@ func (m *mockBookingRepo) List(context.Context, int, int) ([]domain.Booking, err
 func (m *mockBookingRepo) ListByStatus(context.Context, domain.BookingStatus, int, int) ([]domain.Booking, error) { return nil, nil }
 func (m *mockBookingRepo) ListByUserID(context.Context, int64, int, int, *domain.BookingStatus) ([]domain.Booking, error) { return nil, nil }
 func (m *mockBookingRepo) CreateForUser(context.Context, int64, *domain.BookingGuestReq) (*domain.Booking, error) { return nil, nil }
+func (m *mockBookingRepo) UpdateStatus(context.Context, int64, domain.BookingStatus) (bool, error) { return false, nil }
+func (m *mockBookingRepo) AssignDriver(context.Context, int64, int64) (bool, error) { return false, nil }
+func (m *mockBookingRepo) UnassignDriver(context.Context, int64) (bool, error) { return false, nil }
+func (m *mockBookingRepo) StartTrip(context.Context, int64, int64) (bool, error) { return false, nil }
+func (m *mockBookingRepo) CompleteTrip(context.Context, int64, int64) (bool, error) { return false, nil }
+func (m *mockBookingRepo) UpdateForUser(context.Context, int64, int64, domain.GuestPatch) (*domain.Booking, error) {
+	return nil, nil
+}
+func (m *mockBookingRepo) Search(context.Context, domain.BookingFilter, int, int) ([]domain.Booking, error) { return nil, nil }
+func (m *mockBookingRepo) ListEvents(context.Context, int64) ([]domain.BookingEvent, error) {
+	return []domain.BookingEvent{}, nil
+}
+func (m *mockBookingRepo) ListBusy(context.Context, time.Time, time.Time) ([]domain.BusyInterval, error) {
+	return nil, nil
+}
 
 type mockIdempotencyRepo struct {
 	records map[string]int64 // key_hash -> booking_id
@@ -267,13 +305,14 @@ func newMockUsersRepo() *mockUsersRepo {
 	}
 }
 
-func (m *mockUsersRepo) Create(ctx context.Context, email, hash, name, phone string) (*postgres.User, error) {
+func (m *mockUsersRepo) Create(ctx context.Context, email, hash, name, phone, locale string) (*postgres.User, error) {
 	user := &postgres.User{
 		ID: int64(len(m.users) + 1),
 		Email: email,
 		PasswordHash: hash,
 		Name: name,
 		Phone: phone,
+		Locale: locale,
 		Role: "rider",
 	}
 	m.users[email] = user
@@ -300,29 +339,83 @@ func (m *mockUsersRepo) LinkExistingBookings(ctx context.Context, userID int64,
 	return nil
 }
 
+func (m *mockUsersRepo) UpdatePassword(ctx context.Context, userID int64, hash, keepRefreshHash string) error {
+	return nil
+}
+
+func (m *mockUsersRepo) UpdateProfile(ctx context.Context, userID int64, name, phone, locale string) (*postgres.User, error) {
+	return nil, nil
+}
+
+func (m *mockUsersRepo) UpdateEmail(ctx context.Context, userID int64, email string) error {
+	return nil
+}
+
+// mockVehicleRepo knows a single "suv" class seating 6 with room for 6 bags.
+type mockVehicleRepo struct{}
+
+func (m *mockVehicleRepo) Create(ctx context.Context, v *domain.Vehicle) (*domain.Vehicle, error) {
+	return v, nil
+}
+
+func (m *mockVehicleRepo) GetByID(ctx context.Context, id int64) (*domain.Vehicle, error) {
+	return nil, nil
+}
+
+func (m *mockVehicleRepo) List(ctx context.Context, class string, activeOnly bool, limit, offset int) ([]domain.Vehicle, error) {
+	return nil, nil
+}
+
+func (m *mockVehicleRepo) Update(ctx context.Context, id int64, p domain.VehiclePatch) (*domain.Vehicle, error) {
+	return nil, nil
+}
+
+func (m *mockVehicleRepo) Delete(ctx context.Context, id int64) (bool, error) {
+	return false, nil
+}
+
+func (m *mockVehicleRepo) CheckCapacity(ctx context.Context, class string, passengers, luggages int) error {
+	if class != domain.DefaultVehicleClass {
+		return domain.ErrUnknownVehicleClass
+	}
+	if passengers > 6 || luggages > 6 {
+		return &domain.CapacityError{Class: class, Capacity: 6, LuggageCapacity: 6}
+	}
+	return nil
+}
+
+func (m *mockVehicleRepo) ActiveCounts(ctx context.Context) (map[string]int, error) {
+	return map[string]int{domain.DefaultVehicleClass: 1}, nil
+}
+
 // ---------- Test Setup ----------
 
-func setupTestServer() (*httptest.Server, *mockBookingRepo, *mockVerifyRepo, *mockMailer, *mockIdempotencyRepo) {
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Environment = config.EnvDevelopment
+	return &cfg
+}
+
+func setupTestServer() (*httptest.Server, *mockBookingRepo, *mockVerifyRepo, *mockIdempotencyRepo) {
 	bookingRepo := newMockBookingRepo()
 	verifyRepo := newMockVerifyRepo()
-	mailer := &mockMailer{}
 	idempotencyRepo := newMockIdempotencyRepo()
 	usersRepo := newMockUsersRepo()
 	
-	accessHandler := guest.NewAccessHandler(verifyRepo, mailer, usersRepo)
-	bookingsHandler := guest.NewBookingsHandler(bookingRepo, idempotencyRepo, usersRepo)
+	accessHandler := guest.NewAccessHandler(verifyRepo, usersRepo, testConfig())
+	bookingsHandler := guest.NewBookingsHandler(bookingRepo, idempotencyRepo, usersRepo, &mockVehicleRepo{}, geocode.Nop{}, nil, verifyRepo)
 	
 	r := chi.NewRouter()
 	r.Mount("/v1/guest/access", accessHandler.Routes())
 	r.Mount("/v1/guest/bookings", bookingsHandler.Routes())
 	
-	return httptest.NewServer(r), bookingRepo, verifyRepo, mailer, idempotencyRepo
+	return httptest.NewServer(r), bookingRepo, verifyRepo, idempotencyRepo
 }
 
 // ---------- Tests ----------
 
 func TestGuestAccess_RequestAndVerify_Success(t *testing.T) {
-	server, _, verifyRepo, mailer, _ := setupTestServer()
+	server, _, verifyRepo, _ := setupTestServer()
 	defer server.Close()
 	
 	email := "test@example.com"
@@ -339,15 +432,15 @@ func TestGuestAccess_RequestAndVerify_Success(t *testing.T) {
 		t.Fatal("Expected success message")
 	}
 	
-	if mailer.lastTo != email {
-		t.Fatalf("Expected email to %s, got %s", email, mailer.lastTo)
+	if msg := verifyRepo.lastQueued(); msg.Channel != domain.ChannelEmail || msg.ToEmail != email {
+		t.Fatalf("Expected email to %s, got %+v", email, msg)
 	}
 	
-	// Get the code from our mock (in real implementation it would be from email)
-	code := verifyRepo.codes[email]
-	if code == "" {
+	if verifyRepo.codes[email] == "" {
 		t.Fatal("No code stored")
 	}
+	// The raw code only leaves the handler through the email
+	code := codePattern.FindString(verifyRepo.lastQueued().Text)
 	
 	// Test code verification
 	verifyBody := map[string]string{"email": email, "code": code}
@@ -374,7 +467,7 @@ func TestGuestAccess_RequestAndVerify_Success(t *testing.T) {
 }
 
 func TestGuestAccess_InvalidEmail_BadRequest(t *testing.T) {
-	server, _, _, _, _ := setupTestServer()
+	server, _, _, _ := setupTestServer()
 	defer server.Close()
 	
 	tests := []struct {
@@ -394,8 +487,56 @@ func TestGuestAccess_InvalidEmail_BadRequest(t *testing.T) {
 	}
 }
 
+func TestGuestAccess_SMSChannel(t *testing.T) {
+	server, _, verifyRepo, _ := setupTestServer()
+	defer server.Close()
+	request := func() domain.OutboxMessage {
+		postJSON(t, server.URL+"/v1/guest/access/request", map[string]string{"email": "ana@example.com", "channel": "sms"}, http.StatusOK).Body.Close()
+		return verifyRepo.lastQueued()
+	}
+	booking := map[string]any{
+		"rider_name": "Ana", "rider_email": "ana@example.com", "rider_phone": "+14155550100",
+		"pickup": "A", "dropoff": "B", "scheduled_at": time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+		"passengers": 1, "ride_type": "per_ride",
+	}
+
+	// No phone verified yet: the code goes by email.
+	if msg := request(); msg.Channel != domain.ChannelEmail || msg.ToEmail != "ana@example.com" {
+		t.Fatalf("expected email fallback, got %+v", msg)
+	}
+
+	// Anyone can book with Ana's email and their own phone; that phone must not get her code.
+	postJSON(t, server.URL+"/v1/guest/bookings", booking, http.StatusCreated).Body.Close()
+	if msg := request(); msg.Channel != domain.ChannelEmail {
+		t.Fatalf("code texted to an unverified phone: %+v", msg)
+	}
+
+	// A booking made while signed in as Ana verifies its phone.
+	session, err := auth.NewGuestSession("ana@example.com", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(booking)
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/guest/bookings", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+session)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create with session: status %d", resp.StatusCode)
+	}
+	if msg := request(); msg.Channel != domain.ChannelSMS || msg.ToPhone != "+14155550100" || !codePattern.MatchString(msg.Text) {
+		t.Fatalf("expected a text to the verified phone, got %+v", msg)
+	}
+
+	postJSON(t, server.URL+"/v1/guest/access/request", map[string]string{"email": "ana@example.com", "channel": "pigeon"}, http.StatusBadRequest).Body.Close()
+}
+
 func TestGuestBookings_CreateAndGet_Success(t *testing.T) {
-	server, _, _, _, _ := setupTestServer()
+	server, _, _, _ := setupTestServer()
 	defer server.Close()
 	
 	// Create booking
@@ -443,7 +584,7 @@ func TestGuestBookings_CreateAndGet_Success(t *testing.T) {
 }
 
 func TestGuestBookings_CreateWithIdempotency_ReturnsExisting(t *testing.T) {
-	server, _, _, _, _ := setupTestServer()
+	server, _, _, _ := setupTestServer()
 	defer server.Close()
 	
 	idempotencyKey := "test-key-123"
@@ -506,7 +647,7 @@ func TestGuestBookings_CreateWithIdempotency_ReturnsExisting(t *testing.T) {
 }
 
 func TestGuestBookings_InvalidInput_BadRequest(t *testing.T) {
-	server, _, _, _, _ := setupTestServer()
+	server, _, _, _ := setupTestServer()
 	defer server.Close()
 	
 	tests := []struct {
@@ -563,7 +704,7 @@ func TestGuestBookings_InvalidInput_BadRequest(t *testing.T) {
 }
 
 func TestGuestBookings_SessionBasedAccess_RequiresAuth(t *testing.T) {
-	server, bookingRepo, _, _, _ := setupTestServer()
+	server, bookingRepo, _, _ := setupTestServer()
 	defer server.Close()
 	
 	// Create a booking directly in repo
@@ -607,6 +748,95 @@ func TestGuestBookings_SessionBasedAccess_RequiresAuth(t *testing.T) {
 	}
 }
 
+func TestGuestBookings_ListReturnsFullDTO(t *testing.T) {
+	server, bookingRepo, _, _ := setupTestServer()
+	defer server.Close()
+
+	at := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	booking, _ := bookingRepo.CreateGuest(context.Background(), &domain.BookingGuestReq{
+		RiderName: "Test User", RiderEmail: "test@example.com", RiderPhone: "+1234567890",
+		Pickup: "A", Dropoff: "B", ScheduledAt: at,
+		Passengers: 1, Luggages: 0, RideType: domain.RideHourly,
+	})
+	minutes := 180
+	booking.DurationMinutes = &minutes
+	booking.VehicleClass = "suv"
+
+	token, _ := auth.NewGuestSession("test@example.com", 30*time.Minute)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/guest/bookings", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var bookings []domain.BookingDTO
+	if err := json.NewDecoder(resp.Body).Decode(&bookings); err != nil || len(bookings) != 1 {
+		t.Fatalf("decode list: %v, %d bookings", err, len(bookings))
+	}
+	got := bookings[0]
+	if got.DurationMinutes == nil || *got.DurationMinutes != minutes || !got.EndsAt.Equal(at.Add(3*time.Hour)) || got.VehicleClass != "suv" {
+		t.Errorf("list item = %+v, want the full booking", got)
+	}
+}
+
+func TestGuestBookings_PatchAssigned_Conflict(t *testing.T) {
+	server, bookingRepo, _, _ := setupTestServer()
+	defer server.Close()
+
+	booking, _ := bookingRepo.CreateGuest(context.Background(), &domain.BookingGuestReq{
+		RiderName: "Test User", RiderEmail: "test@example.com", RiderPhone: "+1234567890",
+		Pickup: "A", Dropoff: "B", ScheduledAt: time.Now().Add(24 * time.Hour),
+		Passengers: 1, Luggages: 0, RideType: domain.RidePerRide,
+	})
+	booking.Status = domain.BookingAssigned
+
+	patchURL := fmt.Sprintf("%s/v1/guest/bookings/%d?manage_token=%s", server.URL, booking.ID, booking.ManageToken)
+	req, _ := http.NewRequest(http.MethodPatch, patchURL, bytes.NewReader(jsonBytes(map[string]any{"notes": "gate 4"})))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("patch of an assigned booking = %d, want 409", resp.StatusCode)
+	}
+	if booking.Notes != "" {
+		t.Errorf("notes = %q, want the booking unchanged", booking.Notes)
+	}
+}
+
+func TestGuestBookings_CancelCompleted_Conflict(t *testing.T) {
+	server, bookingRepo, _, _ := setupTestServer()
+	defer server.Close()
+
+	booking, _ := bookingRepo.CreateGuest(context.Background(), &domain.BookingGuestReq{
+		RiderName: "Test User", RiderEmail: "test@example.com", RiderPhone: "+1234567890",
+		Pickup: "A", Dropoff: "B", ScheduledAt: time.Now().Add(2 * time.Hour),
+		Passengers: 1, Luggages: 0, RideType: domain.RidePerRide,
+	})
+	booking.Status = domain.BookingCompleted
+
+	cancelURL := fmt.Sprintf("%s/v1/guest/bookings/%d?manage_token=%s", server.URL, booking.ID, booking.ManageToken)
+	resp := del(t, cancelURL, http.StatusConflict)
+	resp.Body.Close()
+
+	if booking.Status != domain.BookingCompleted {
+		t.Fatalf("Expected status to stay completed, got %s", booking.Status)
+	}
+
+	booking.Status = domain.BookingConfirmed
+	resp = del(t, cancelURL, http.StatusNoContent)
+	resp.Body.Close()
+
+	if booking.Status != domain.BookingCanceled {
+		t.Fatalf("Expected status canceled, got %s", booking.Status)
+	}
+}
+
 // ---------- Helper Functions ----------
 
 func postJSON(t *testing.T, url string, data interface{}, expectedStatus int) *http.Response {
@@ -640,6 +870,22 @@ func get(t *testing.T, url string, expectedStatus int) *http.Response {
 	return resp
 }
 
+func del(t *testing.T, url string, expectedStatus int) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequest("DELETE", url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE %s failed: %v", url, err)
+	}
+
+	if resp.StatusCode != expectedStatus {
+		t.Fatalf("DELETE %s: expected status %d, got %d", url, expectedStatus, resp.StatusCode)
+	}
+
+	return resp
+}
+
 func jsonBytes(data interface{}) []byte {
 	b, _ := json.Marshal(data)
 	return b
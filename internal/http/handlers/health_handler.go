@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Pinger is what readiness checks the database with; *pgxpool.Pool fits.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Readiness answers /readyz. It fails once Drain is called, so load balancers
+// stop sending traffic before the server shuts down, and while the database
+// is unreachable.
+type Readiness struct {
+	DB       Pinger
+	draining atomic.Bool
+}
+
+func NewReadiness(db Pinger) *Readiness {
+	return &Readiness{DB: db}
+}
+
+// Drain makes every later check fail.
+func (h *Readiness) Drain() { h.draining.Store(true) }
+
+func (h *Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"status": "draining"}`))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if err := h.DB.Ping(ctx); err != nil {
+		log.Printf("readiness: database ping failed: %v", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"status": "database unavailable"}`))
+		return
+	}
+	_, _ = w.Write([]byte(`{"status": "ok"}`))
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+)
+
+// JWKS serves the public keys access tokens can be verified with, so other
+// services can check our tokens without a shared secret.
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_ = json.NewEncoder(w).Encode(auth.CurrentKeySet().JWKS())
+}
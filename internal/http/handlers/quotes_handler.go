@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/geocode"
+	"github.com/diagnosis/luxsuv-bookings/internal/pricing"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// QuotesHandler prices trips before they are booked. It is public so guests can
+// see a fare before creating a booking with the returned quote_id.
+type QuotesHandler struct {
+	Quotes   postgres.QuoteRepo
+	Rates    *pricing.RateCard
+	Geocoder geocode.Geocoder
+}
+
+func NewQuotesHandler(q postgres.QuoteRepo, rates *pricing.RateCard, g geocode.Geocoder) *QuotesHandler {
+	return &QuotesHandler{Quotes: q, Rates: rates, Geocoder: g}
+}
+
+func (h *QuotesHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.create)
+	r.Get("/{id}", h.getByID)
+	return r
+}
+
+func (h *QuotesHandler) create(w http.ResponseWriter, r *http.Request) {
+	var in domain.QuoteReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+	in.Pickup = utils.NormalizeString(in.Pickup)
+	in.Dropoff = utils.NormalizeString(in.Dropoff)
+	in.VehicleClass = strings.ToLower(utils.NormalizeString(in.VehicleClass))
+	if in.VehicleClass == "" {
+		in.VehicleClass = domain.DefaultVehicleClass
+	}
+
+	if in.Pickup == "" || in.Dropoff == "" || in.ScheduledAt.IsZero() {
+		response.WriteError(w, http.StatusBadRequest, "Missing required fields: pickup, dropoff, scheduled_at", response.CodeInvalidInput)
+		return
+	}
+	if in.ScheduledAt.Before(time.Now()) {
+		response.WriteError(w, http.StatusBadRequest, "Scheduled time must be in the future", response.CodePastDateTime)
+		return
+	}
+	if in.RideType != domain.RidePerRide && in.RideType != domain.RideHourly {
+		response.WriteError(w, http.StatusBadRequest, "Ride type must be 'per_ride' or 'hourly'", response.CodeInvalidInput)
+		return
+	}
+	if in.DistanceMiles < 0 || in.DurationMinutes < 0 {
+		response.WriteError(w, http.StatusBadRequest, "distance_miles and duration_minutes must not be negative", response.CodeInvalidInput)
+		return
+	}
+	if in.RideType == domain.RideHourly {
+		if err := domain.ValidateDurationRange(in.DurationMinutes); err != nil {
+			response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+			return
+		}
+	}
+
+	if in.RideType == domain.RidePerRide {
+		// The client's estimate can raise the fare but never lower it below
+		// the straight line between the geocoded ends at top speed.
+		in.DistanceMiles = max(in.DistanceMiles, h.straightLineMiles(r.Context(), in.Pickup, in.Dropoff))
+		in.DurationMinutes = max(in.DurationMinutes, h.Rates.MinTripMinutes(in.DistanceMiles))
+	}
+
+	q, err := h.Rates.Quote(in)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return
+	}
+	q.ExpiresAt = time.Now().Add(pricing.QuoteTTL)
+	if err := h.Quotes.Create(r.Context(), q); err != nil {
+		log.Printf("failed to store quote: %v", err)
+		response.InternalError(w, "Failed to create quote")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(q)
+}
+
+// straightLineMiles geocodes both ends of a trip and returns the distance
+// between them, or 0 when either end is unknown.
+func (h *QuotesHandler) straightLineMiles(ctx context.Context, pickup, dropoff string) float64 {
+	from, _ := geocode.Resolve(ctx, h.Geocoder, pickup, nil)
+	to, _ := geocode.Resolve(ctx, h.Geocoder, dropoff, nil)
+	if from == nil || to == nil {
+		return 0
+	}
+	return domain.DistanceMiles(*from, *to)
+}
+
+func (h *QuotesHandler) getByID(w http.ResponseWriter, r *http.Request) {
+	q, err := h.Quotes.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("failed to get quote: %v", err)
+		response.InternalError(w, "Failed to retrieve quote")
+		return
+	}
+	if q == nil {
+		response.NotFound(w, "Quote not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(q)
+}
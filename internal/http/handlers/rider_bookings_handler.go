@@ -2,23 +2,34 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/geofence"
 	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/geocode"
 	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
 type RiderBookingsHandler struct {
-	Bookings *postgres.BookingRepoImpl
+	Bookings postgres.BookingRepo
 	Users    postgres.UsersRepo
+	Vehicles postgres.VehicleRepo
+	Geocoder geocode.Geocoder
+	// ServiceArea limits where pickups and dropoffs may be; nil allows anywhere.
+	ServiceArea *geofence.Area
 }
 
-func NewRiderBookingsHandler(b *postgres.BookingRepoImpl, u postgres.UsersRepo) *RiderBookingsHandler {
-	return &RiderBookingsHandler{Bookings: b, Users: u}
+func NewRiderBookingsHandler(b postgres.BookingRepo, u postgres.UsersRepo, v postgres.VehicleRepo, g geocode.Geocoder, area *geofence.Area) *RiderBookingsHandler {
+	return &RiderBookingsHandler{Bookings: b, Users: u, Vehicles: v, Geocoder: g, ServiceArea: area}
 }
 
 func (h *RiderBookingsHandler) Routes() chi.Router {
@@ -26,6 +37,8 @@ func (h *RiderBookingsHandler) Routes() chi.Router {
 	r.Use(mw.RequireJWT)
 	r.Get("/", h.list)
 	r.Get("/{id}", h.getByID)
+	r.Get("/{id}/history", h.history)
+	r.Patch("/{id}", h.patch)
 	r.Delete("/{id}", h.cancel)
 	r.Post("/", h.create)
 	return r
@@ -39,6 +52,15 @@ type riderCreateReq struct {
 	Passengers  int             `json:"passengers"`
 	Luggages    int             `json:"luggages"`
 	RideType    domain.RideType `json:"ride_type"`
+	QuoteID     string          `json:"quote_id"`
+	// DurationMinutes is required for hourly rides.
+	DurationMinutes *int   `json:"duration_minutes"`
+	VehicleClass    string `json:"vehicle_class"`
+	// Optional client-side geocoding; the server geocodes the addresses otherwise.
+	PickupLocation  *domain.Location `json:"pickup_location"`
+	DropoffLocation *domain.Location `json:"dropoff_location"`
+	// ReminderChannel is "email" (the default) or "sms" to the account's phone.
+	ReminderChannel string `json:"reminder_channel"`
 }
 
 func (h *RiderBookingsHandler) create(w http.ResponseWriter, r *http.Request) {
@@ -61,11 +83,11 @@ func (h *RiderBookingsHandler) create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "scheduled_at must be future", http.StatusBadRequest)
 		return
 	}
-	if in.Passengers <= 0 || in.Passengers > 8 {
+	if in.Passengers <= 0 {
 		http.Error(w, "invalid passengers", http.StatusBadRequest)
 		return
 	}
-	if in.Luggages < 0 || in.Luggages > 10 {
+	if in.Luggages < 0 {
 		http.Error(w, "invalid luggages", http.StatusBadRequest)
 		return
 	}
@@ -73,26 +95,67 @@ func (h *RiderBookingsHandler) create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "ride_type must be 'per_ride' or 'hourly'", http.StatusBadRequest)
 		return
 	}
+	if err := domain.ValidateDuration(in.RideType, in.DurationMinutes); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return
+	}
+	channel, ok := domain.ParseChannel(in.ReminderChannel)
+	if !ok {
+		http.Error(w, "reminder_channel must be 'email' or 'sms'", http.StatusBadRequest)
+		return
+	}
+	in.VehicleClass = strings.ToLower(utils.NormalizeString(in.VehicleClass))
+	if in.VehicleClass == "" {
+		in.VehicleClass = domain.DefaultVehicleClass
+	}
+	if !PartyFits(w, r, h.Vehicles, in.VehicleClass, in.Passengers, in.Luggages) {
+		return
+	}
 
 	// fetch rider contact
-	u, err := h.Users.FindByEmail(r.Context(), claims.Email)
+	u, err := h.Users.FindByID(r.Context(), claims.Sub)
 	if err != nil || u == nil {
 		http.Error(w, "user not found", http.StatusUnauthorized)
 		return
 	}
 
-	b, err := h.Bookings.CreateForUser(r.Context(), claims.Sub, &domain.BookingGuestReq{
-		RiderName:   u.Name,
-		RiderEmail:  u.Email,
-		RiderPhone:  u.Phone,
-		Pickup:      in.Pickup,
-		Dropoff:     in.Dropoff,
-		ScheduledAt: in.ScheduledAt,
-		Notes:       in.Notes,
-		Passengers:  in.Passengers,
-		Luggages:    in.Luggages,
-		RideType:    in.RideType,
-	})
+	req := &domain.BookingGuestReq{
+		RiderName:       u.Name,
+		RiderEmail:      u.Email,
+		RiderPhone:      u.Phone,
+		Pickup:          in.Pickup,
+		Dropoff:         in.Dropoff,
+		ScheduledAt:     in.ScheduledAt,
+		Notes:           in.Notes,
+		Passengers:      in.Passengers,
+		Luggages:        in.Luggages,
+		RideType:        in.RideType,
+		QuoteID:         in.QuoteID,
+		DurationMinutes: in.DurationMinutes,
+		VehicleClass:    in.VehicleClass,
+		PickupLocation:  in.PickupLocation,
+		DropoffLocation: in.DropoffLocation,
+		Locale:          u.Locale,
+		ReminderChannel: channel,
+	}
+	if err := geocode.ResolveTrip(r.Context(), h.Geocoder, req, h.ServiceArea == nil); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error(), response.CodeInvalidInput)
+		return
+	}
+	if err := h.ServiceArea.CheckTrip(req.PickupLocation, req.DropoffLocation); err != nil {
+		WriteAreaError(w, err)
+		return
+	}
+
+	b, err := h.Bookings.CreateForUser(r.Context(), claims.Sub, req)
+	if errors.Is(err, domain.ErrQuoteInvalid) {
+		response.WriteError(w, http.StatusBadRequest, "Quote is expired, already used, or does not match this booking", response.CodeInvalidInput)
+		return
+	}
+	if errors.Is(err, domain.ErrSlotFull) {
+		response.Conflict(w, "No vehicles of this class are available at the requested time")
+		return
+	}
 	if err != nil {
 		http.Error(w, "could not create booking", http.StatusInternalServerError)
 		return
@@ -100,13 +163,7 @@ func (h *RiderBookingsHandler) create(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(domain.BookingDTO{
-		ID: b.ID, Status: string(b.Status),
-		RiderName: b.RiderName, RiderEmail: b.RiderEmail, RiderPhone: b.RiderPhone,
-		Pickup: b.Pickup, Dropoff: b.Dropoff, ScheduledAt: b.ScheduledAt, Notes: b.Notes,
-		Passengers: b.Passengers, Luggages: b.Luggages, RideType: string(b.RideType),
-		DriverID: b.DriverID, CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt,
-	})
+	_ = json.NewEncoder(w).Encode(b.DTO())
 }
 
 func (h *RiderBookingsHandler) list(w http.ResponseWriter, r *http.Request) {
@@ -144,18 +201,46 @@ func (h *RiderBookingsHandler) list(w http.ResponseWriter, r *http.Request) {
 
 	out := make([]domain.BookingDTO, 0, len(bs))
 	for _, b := range bs {
-		out = append(out, domain.BookingDTO{
-			ID: b.ID, Status: string(b.Status),
-			RiderName: b.RiderName, RiderEmail: b.RiderEmail, RiderPhone: b.RiderPhone,
-			Pickup: b.Pickup, Dropoff: b.Dropoff, ScheduledAt: b.ScheduledAt, Notes: b.Notes,
-			Passengers: b.Passengers, Luggages: b.Luggages, RideType: string(b.RideType),
-			DriverID: b.DriverID, CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt,
-		})
+		out = append(out, b.DTO())
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(out)
 }
 
+// history lists the events of one of the rider's own bookings.
+func (h *RiderBookingsHandler) history(w http.ResponseWriter, r *http.Request) {
+	claims := mw.Claims(r)
+	if claims == nil || claims.Role != "rider" {
+		response.Forbidden(w, "Rider access required")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
+		return
+	}
+
+	b, err := h.Bookings.GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get booking for history: %v", err)
+		response.InternalError(w, "Failed to retrieve booking history")
+		return
+	}
+	if b == nil || b.UserID == nil || *b.UserID != claims.Sub {
+		response.NotFound(w, "Booking not found")
+		return
+	}
+
+	events, err := h.Bookings.ListEvents(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to list booking events: %v", err)
+		response.InternalError(w, "Failed to retrieve booking history")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
+
 func (h *RiderBookingsHandler) getByID(w http.ResponseWriter, r *http.Request) {
 	claims := mw.Claims(r)
 	if claims == nil || claims.Role != "rider" {
@@ -174,18 +259,64 @@ func (h *RiderBookingsHandler) getByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
 	}
-	if b == nil || b.RiderEmail != claims.Email && b.DriverID != &claims.Sub && b.ID == 0 {
-		// simplest ownership check for rider: by user_id (preferred) or fallback by email if you haven’t backfilled
-		if b == nil || b.RiderEmail != claims.Email {
-			http.NotFound(w, r)
+	// Ownership is by account, not email: riders can change their email.
+	if b == nil || b.UserID == nil || *b.UserID != claims.Sub {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(b)
+}
+
+func (h *RiderBookingsHandler) patch(w http.ResponseWriter, r *http.Request) {
+	claims := mw.Claims(r)
+	if claims == nil || claims.Role != "rider" || claims.Sub == 0 {
+		response.Forbidden(w, "Rider account required")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "Invalid booking ID")
+		return
+	}
+
+	in, ok := DecodeBookingPatch(w, r, h.Geocoder, h.ServiceArea)
+	if !ok {
+		return
+	}
+	if in.ChangesParty() {
+		existing, err := h.Bookings.GetByID(r.Context(), id)
+		if err != nil {
+			log.Printf("failed to get booking %d for capacity check: %v", id, err)
+			response.InternalError(w, "Failed to update booking")
+			return
+		}
+		if existing == nil || existing.UserID == nil || *existing.UserID != claims.Sub {
+			response.NotFound(w, "Booking not found")
+			return
+		}
+		class, pax, bags := in.Party(*existing)
+		if !PartyFits(w, r, h.Vehicles, class, pax, bags) {
 			return
 		}
 	}
-	// optional: if you already set user_id on bookings, check that instead of email
-	// (add user_id to Booking struct if you want)
 
+	b, err := h.Bookings.UpdateForUser(r.Context(), id, claims.Sub, in)
+	if WritePatchError(w, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("failed to update booking %d for user %d: %v", id, claims.Sub, err)
+		response.InternalError(w, "Failed to update booking")
+		return
+	}
+	if b == nil {
+		response.NotFound(w, "Booking not found")
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(b)
+	_ = json.NewEncoder(w).Encode(b.DTO())
 }
 
 func (h *RiderBookingsHandler) cancel(w http.ResponseWriter, r *http.Request) {
@@ -205,18 +336,17 @@ func (h *RiderBookingsHandler) cancel(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
 	}
-	if b == nil {
+	if b == nil || b.UserID == nil || *b.UserID != claims.Sub {
 		http.NotFound(w, r)
 		return
 	}
-	// ownership: prefer user_id check; fallback to email
-	if b.RiderEmail != claims.Email {
-		http.Error(w, "forbidden", http.StatusForbidden)
-		return
-	}
 
 	// Soft cancel (reuse your repo CancelWithToken, or add a CancelByID)
 	if _, err := h.Bookings.CancelWithToken(r.Context(), id, b.ManageToken); err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			response.Conflict(w, "Booking cannot be canceled in its current status")
+			return
+		}
 		http.Error(w, "cancel error", http.StatusInternalServerError)
 		return
 	}
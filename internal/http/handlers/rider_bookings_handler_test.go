@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/handlers"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+)
+
+// fakeBookings holds bookings in memory; methods the tests don't reach are
+// left to the embedded nil interface.
+type fakeBookings struct {
+	postgres.BookingRepo
+	bookings map[int64]*domain.Booking
+}
+
+func (f *fakeBookings) GetByID(_ context.Context, id int64) (*domain.Booking, error) {
+	return f.bookings[id], nil
+}
+
+func (f *fakeBookings) CancelWithToken(_ context.Context, id int64, token string) (bool, error) {
+	b := f.bookings[id]
+	if b == nil || b.ManageToken != token {
+		return false, nil
+	}
+	b.Status = domain.BookingCanceled
+	return true, nil
+}
+
+func riderRequest(t *testing.T, method, url string, sub int64, email string) *http.Response {
+	t.Helper()
+	token, err := auth.NewAccessToken(sub, email, auth.RoleRider, auth.ScopeForRole(auth.RoleRider), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestRiderBookings_OwnershipSurvivesEmailChange(t *testing.T) {
+	userID := int64(42)
+	repo := &fakeBookings{bookings: map[int64]*domain.Booking{
+		1: {ID: 1, UserID: &userID, RiderEmail: "old@example.com", ManageToken: "tok", Status: domain.BookingPending, ScheduledAt: time.Now().Add(24 * time.Hour)},
+	}}
+	server := httptest.NewServer(handlers.NewRiderBookingsHandler(repo, nil, nil, nil, nil).Routes())
+	defer server.Close()
+
+	// The rider confirmed a new email, so their token no longer matches the booking's.
+	if resp := riderRequest(t, http.MethodGet, server.URL+"/1", userID, "new@example.com"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("get = %d, want 200", resp.StatusCode)
+	}
+	if resp := riderRequest(t, http.MethodGet, server.URL+"/1", 7, "old@example.com"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("get by another account with the old email = %d, want 404", resp.StatusCode)
+	}
+	if resp := riderRequest(t, http.MethodDelete, server.URL+"/1", userID, "new@example.com"); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("cancel = %d, want 204", resp.StatusCode)
+	}
+	if repo.bookings[1].Status != domain.BookingCanceled {
+		t.Errorf("status = %s, want canceled", repo.bookings[1].Status)
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/diagnosis/luxsuv-bookings/internal/config"
+	mw "github.com/diagnosis/luxsuv-bookings/internal/http/middleware"
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+	"github.com/diagnosis/luxsuv-bookings/internal/outbox"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/templates"
+	"github.com/diagnosis/luxsuv-bookings/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// RiderProfileHandler lets a signed-in user read and change their own account.
+type RiderProfileHandler struct {
+	Users  postgres.UsersRepo
+	Verify postgres.VerifyRepo
+	Config *config.Config
+}
+
+func NewRiderProfileHandler(users postgres.UsersRepo, verify postgres.VerifyRepo, cfg *config.Config) *RiderProfileHandler {
+	return &RiderProfileHandler{Users: users, Verify: verify, Config: cfg}
+}
+
+func (h *RiderProfileHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(mw.RequireJWT)
+	r.Get("/", h.get)
+	r.Patch("/", h.update)
+	r.Post("/password", h.changePassword)
+	r.Post("/email", h.requestEmailChange)
+	return r
+}
+
+type profileDTO struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	Role      string    `json:"role"`
+	Locale    string    `json:"locale"`
+	Verified  bool      `json:"verified"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// currentUser loads the account behind the token, writing the error response if it can't.
+func (h *RiderProfileHandler) currentUser(w http.ResponseWriter, r *http.Request) *postgres.User {
+	claims := mw.Claims(r)
+	if claims == nil || claims.Sub == 0 {
+		response.WriteError(w, http.StatusUnauthorized, "Account required", response.CodeUnauthorized)
+		return nil
+	}
+	u, err := h.Users.FindByID(r.Context(), claims.Sub)
+	if err != nil || u == nil {
+		response.NotFound(w, "User not found")
+		return nil
+	}
+	return u
+}
+
+func (h *RiderProfileHandler) writeProfile(w http.ResponseWriter, r *http.Request, u *postgres.User) {
+	verified, err := h.Verify.IsUserVerified(r.Context(), u.ID)
+	if err != nil {
+		log.Printf("failed to check verification for user %d: %v", u.ID, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(profileDTO{
+		ID: u.ID, Email: u.Email, Name: u.Name, Phone: u.Phone, Role: u.Role, Locale: u.Locale,
+		Verified: verified, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt,
+	})
+}
+
+func (h *RiderProfileHandler) get(w http.ResponseWriter, r *http.Request) {
+	u := h.currentUser(w, r)
+	if u == nil {
+		return
+	}
+	h.writeProfile(w, r, u)
+}
+
+func (h *RiderProfileHandler) update(w http.ResponseWriter, r *http.Request) {
+	u := h.currentUser(w, r)
+	if u == nil {
+		return
+	}
+	var in struct {
+		Name   *string `json:"name"`
+		Phone  *string `json:"phone"`
+		Locale *string `json:"locale"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	name, phone, locale := u.Name, u.Phone, u.Locale
+	if in.Name != nil {
+		name = utils.NormalizeString(*in.Name)
+		if name == "" {
+			response.BadRequest(w, "Name cannot be empty")
+			return
+		}
+	}
+	if in.Phone != nil {
+		phone = utils.NormalizePhone(*in.Phone)
+		if !utils.IsValidPhone(phone) {
+			response.BadRequest(w, "Invalid phone number")
+			return
+		}
+	}
+	if in.Locale != nil {
+		l, ok := templates.Match(*in.Locale)
+		if !ok {
+			response.BadRequest(w, "Unsupported locale")
+			return
+		}
+		locale = l
+	}
+
+	updated, err := h.Users.UpdateProfile(r.Context(), u.ID, name, phone, locale)
+	if err != nil {
+		log.Printf("failed to update profile for user %d: %v", u.ID, err)
+		response.InternalError(w, "Failed to update profile")
+		return
+	}
+	h.writeProfile(w, r, updated)
+}
+
+func (h *RiderProfileHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	u := h.currentUser(w, r)
+	if u == nil {
+		return
+	}
+	var in struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+		// RefreshToken is this device's, which stays signed in when given.
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.CurrentPassword == "" || in.NewPassword == "" {
+		response.BadRequest(w, "Current and new password are required")
+		return
+	}
+	if len(in.NewPassword) < 8 {
+		response.BadRequest(w, "Password must be at least 8 characters")
+		return
+	}
+
+	ok, err := argon2id.ComparePasswordAndHash(in.CurrentPassword, u.PasswordHash)
+	if err != nil || !ok {
+		response.WriteError(w, http.StatusUnauthorized, "Current password is incorrect", response.CodeUnauthorized)
+		return
+	}
+
+	hash, err := argon2id.CreateHash(in.NewPassword, argon2id.DefaultParams)
+	if err != nil {
+		response.InternalError(w, "Failed to hash password")
+		return
+	}
+	// Other devices must sign in again with the new password; so must this
+	// one if it didn't send its refresh token.
+	keep := ""
+	if in.RefreshToken != "" {
+		keep = auth.HashToken(in.RefreshToken)
+	}
+	if err := h.Users.UpdatePassword(r.Context(), u.ID, hash, keep); err != nil {
+		log.Printf("failed to update password for user %d: %v", u.ID, err)
+		response.InternalError(w, "Failed to update password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RiderProfileHandler) requestEmailChange(w http.ResponseWriter, r *http.Request) {
+	u := h.currentUser(w, r)
+	if u == nil {
+		return
+	}
+	var in struct {
+		NewEmail string `json:"new_email"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.NewEmail == "" || in.Password == "" {
+		response.BadRequest(w, "New email and password are required")
+		return
+	}
+	newEmail := utils.NormalizeEmail(in.NewEmail)
+	if !utils.IsValidEmail(newEmail) {
+		response.BadRequest(w, "Invalid email format")
+		return
+	}
+	if strings.EqualFold(newEmail, u.Email) {
+		response.BadRequest(w, "New email matches the current one")
+		return
+	}
+
+	ok, err := argon2id.ComparePasswordAndHash(in.Password, u.PasswordHash)
+	if err != nil || !ok {
+		response.WriteError(w, http.StatusUnauthorized, "Password is incorrect", response.CodeUnauthorized)
+		return
+	}
+	if other, err := h.Users.FindByEmail(r.Context(), newEmail); err == nil && other != nil {
+		response.WriteError(w, http.StatusConflict, "Email is already in use", response.CodeEmailExists)
+		return
+	}
+
+	tok := uuid.NewString()
+	confirmURL := h.Config.FrontendBaseURL + "/confirm-email?token=" + tok
+
+	// The link goes to the new address: clicking it proves the rider owns it.
+	mail, err := outbox.Email(newEmail, u.Name, u.Locale, "email_change",
+		templates.LinkData{Name: u.Name, URL: confirmURL, Hours: 2})
+	if err != nil {
+		log.Printf("failed to render email change confirmation: %v", err)
+		response.InternalError(w, "Failed to send confirmation email")
+		return
+	}
+	if err := h.Verify.CreateEmailChange(r.Context(), u.ID, newEmail, tok, time.Now().Add(2*time.Hour), mail); err != nil {
+		log.Printf("failed to create email change token: %v", err)
+		response.InternalError(w, "Failed to start email change")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message": "A confirmation link has been sent to the new email address",
+	})
+}
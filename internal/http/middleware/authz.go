@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/http/response"
+)
+
+// RequireRole allows the request only if the JWT role (set by RequireJWT) is one of roles.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := Claims(r)
+			if claims == nil {
+				response.Unauthorized(w, "Authentication required")
+				return
+			}
+			for _, role := range roles {
+				if claims.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			response.Forbidden(w, "Role "+strings.Join(roles, " or ")+" required")
+		})
+	}
+}
+
+// RequireScope allows the request only if the JWT (set by RequireJWT) grants every scope in scopes.
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := Claims(r)
+			if claims == nil {
+				response.Unauthorized(w, "Authentication required")
+				return
+			}
+			for _, s := range scopes {
+				if !claims.HasScope(s) {
+					response.Forbidden(w, "Missing scope "+s)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/diagnosis/luxsuv-bookings/internal/audit"
 	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
 )
 
@@ -32,6 +33,7 @@ func RequireGuestSession(next http.Handler) http.Handler {
 			return
 		}
 		ctx := context.WithValue(r.Context(), CtxClaims, claims)
+		ctx = audit.WithActor(ctx, audit.FromClaims(claims))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 
@@ -57,6 +59,7 @@ func OptionalGuestSession(next http.Handler) http.Handler {
 		if tok != "" {
 			if claims, err := auth.Parse(tok); err == nil && claims.Role == "guest" {
 				ctx := context.WithValue(r.Context(), CtxClaims, claims)
+				ctx = audit.WithActor(ctx, audit.FromClaims(claims))
 				r = r.WithContext(ctx)
 			}
 		}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/diagnosis/luxsuv-bookings/internal/audit"
 	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
 )
 
@@ -26,6 +27,7 @@ func RequireJWT(next http.Handler) http.Handler {
 			return
 		}
 		ctx := context.WithValue(r.Context(), CtxClaims, claims)
+		ctx = audit.WithActor(ctx, audit.FromClaims(claims))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
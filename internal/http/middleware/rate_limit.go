@@ -141,4 +141,4 @@ func getClientIP(r *http.Request) string {
 		return r.RemoteAddr
 	}
 	return ip
-}
\ No newline at end of file
+}
@@ -58,6 +58,7 @@ const (
 	CodePastDateTime     = "PAST_DATETIME"
 	CodeEmailExists      = "EMAIL_EXISTS"
 	CodeBookingCanceled  = "BOOKING_CANCELED"
+	CodeOutsideServiceArea = "OUTSIDE_SERVICE_AREA"
 )
 
 // Convenience functions for common errors
@@ -0,0 +1,120 @@
+// Package jobs runs periodic background work, such as purging expired tokens,
+// on exactly one API instance at a time. Each job is guarded by a Postgres
+// advisory lock, and every run is recorded in job_runs with what it processed
+// and the error it failed with, if any.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+)
+
+type Job struct {
+	// Name identifies the job in job_runs and names its advisory lock.
+	Name string
+	// Every is how long after the start of the latest run, on any instance,
+	// the job is due again.
+	Every time.Duration
+	// Timeout bounds one run; zero means Every.
+	Timeout time.Duration
+	// Run does the work and reports how many items it processed.
+	Run func(ctx context.Context) (int64, error)
+}
+
+type Runner struct {
+	Repo postgres.JobRepo
+	// Instance names this process in job_runs.
+	Instance string
+	// Poll is the longest a due job waits to be noticed.
+	Poll time.Duration
+
+	jobs []Job
+}
+
+func NewRunner(repo postgres.JobRepo) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		Repo:     repo,
+		Instance: fmt.Sprintf("%s:%d", host, os.Getpid()),
+		Poll:     30 * time.Second,
+	}
+}
+
+// Register adds j to the jobs Run starts. It must be called before Run.
+func (r *Runner) Register(j Job) {
+	r.jobs = append(r.jobs, j)
+}
+
+// Run polls every registered job until ctx is done, then waits for runs in
+// progress to return.
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range r.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.loop(ctx, j)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, j Job) {
+	t := time.NewTicker(min(r.Poll, j.Every))
+	defer t.Stop()
+	for {
+		if _, err := r.RunOnce(ctx, j); err != nil {
+			log.Printf("jobs: %s: %v", j.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce runs j if it is due and no other instance is running it, reporting
+// whether it ran. The job's own failure is recorded in job_runs and returned.
+func (r *Runner) RunOnce(ctx context.Context, j Job) (bool, error) {
+	release, ok, err := r.Repo.TryLock(ctx, j.Name)
+	if err != nil || !ok {
+		return false, err
+	}
+	defer release()
+
+	last, err := r.Repo.LastStarted(ctx, j.Name)
+	if err != nil {
+		return false, err
+	}
+	if !last.IsZero() && time.Since(last) < j.Every {
+		return false, nil
+	}
+
+	id, err := r.Repo.StartRun(ctx, j.Name, r.Instance)
+	if err != nil {
+		return false, err
+	}
+	timeout := j.Timeout
+	if timeout == 0 {
+		timeout = j.Every
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	affected, runErr := j.Run(runCtx)
+	cancel()
+
+	// Record the outcome even if ctx was canceled during the run.
+	if err := r.Repo.FinishRun(context.WithoutCancel(ctx), id, affected, runErr); err != nil {
+		log.Printf("jobs: %s: record run %d: %v", j.Name, id, err)
+	}
+	if runErr == nil && affected > 0 {
+		log.Printf("jobs: %s processed %d", j.Name, affected)
+	}
+	return true, runErr
+}
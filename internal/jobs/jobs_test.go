@@ -0,0 +1,97 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/jobs"
+)
+
+// fakeRepo is a single-database stand-in: locks are shared by every Runner using it.
+type fakeRepo struct {
+	locked map[string]bool
+	runs   []domain.JobRun
+}
+
+func newFakeRepo() *fakeRepo { return &fakeRepo{locked: map[string]bool{}} }
+
+func (f *fakeRepo) TryLock(ctx context.Context, job string) (func(), bool, error) {
+	if f.locked[job] {
+		return nil, false, nil
+	}
+	f.locked[job] = true
+	return func() { f.locked[job] = false }, true, nil
+}
+
+func (f *fakeRepo) LastStarted(ctx context.Context, job string) (time.Time, error) {
+	var last time.Time
+	for _, r := range f.runs {
+		if r.Job == job && r.StartedAt.After(last) {
+			last = r.StartedAt
+		}
+	}
+	return last, nil
+}
+
+func (f *fakeRepo) StartRun(ctx context.Context, job, instance string) (int64, error) {
+	f.runs = append(f.runs, domain.JobRun{ID: int64(len(f.runs) + 1), Job: job, Instance: instance, StartedAt: time.Now()})
+	return int64(len(f.runs)), nil
+}
+
+func (f *fakeRepo) FinishRun(ctx context.Context, id, affected int64, runErr error) error {
+	r := &f.runs[id-1]
+	now := time.Now()
+	r.FinishedAt, r.Affected = &now, affected
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	return nil
+}
+
+func (f *fakeRepo) Latest(ctx context.Context) ([]domain.JobRun, error) { return f.runs, nil }
+
+func (f *fakeRepo) Purge(ctx context.Context, olderThan time.Duration) (int64, error) { return 0, nil }
+
+func TestRunOnce(t *testing.T) {
+	repo := newFakeRepo()
+	a, b := jobs.NewRunner(repo), jobs.NewRunner(repo)
+	a.Instance, b.Instance = "a", "b"
+
+	calls := 0
+	job := jobs.Job{Name: "purge", Every: time.Hour, Run: func(ctx context.Context) (int64, error) {
+		calls++
+		// While a runs the job, b must skip it.
+		if ran, err := b.RunOnce(ctx, jobs.Job{Name: "purge", Every: time.Hour}); ran || err != nil {
+			t.Errorf("second instance ran a locked job (err %v)", err)
+		}
+		return 3, nil
+	}}
+
+	if ran, err := a.RunOnce(context.Background(), job); !ran || err != nil {
+		t.Fatalf("first run: ran=%v err=%v", ran, err)
+	}
+	if ran, _ := b.RunOnce(context.Background(), job); ran {
+		t.Error("job ran again before it was due")
+	}
+	if calls != 1 || len(repo.runs) != 1 || repo.runs[0].Affected != 3 || repo.runs[0].Instance != "a" || repo.runs[0].FinishedAt == nil {
+		t.Fatalf("calls=%d runs=%+v", calls, repo.runs)
+	}
+}
+
+func TestRunOnceRecordsError(t *testing.T) {
+	repo := newFakeRepo()
+	r := jobs.NewRunner(repo)
+	boom := errors.New("boom")
+	_, err := r.RunOnce(context.Background(), jobs.Job{Name: "flaky", Every: time.Minute, Run: func(context.Context) (int64, error) {
+		return 0, boom
+	}})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(repo.runs) != 1 || repo.runs[0].Error != "boom" || repo.locked["flaky"] {
+		t.Fatalf("runs=%+v locked=%v", repo.runs, repo.locked)
+	}
+}
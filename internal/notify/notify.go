@@ -0,0 +1,141 @@
+// Package notify renders the emails riders get when their booking is created,
+// edited, confirmed, assigned or canceled, and the one drivers get for a new
+// ride.
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/templates"
+)
+
+// Kind is a booking lifecycle event riders are told about. Each kind is the
+// name of its email in the templates package.
+type Kind string
+
+const (
+	BookingCreated   Kind = "booking_created"
+	BookingUpdated   Kind = "booking_updated"
+	BookingConfirmed Kind = "booking_confirmed"
+	BookingAssigned  Kind = "booking_assigned"
+	BookingCanceled  Kind = "booking_canceled"
+	// DriverAssigned goes to the driver rather than the rider.
+	DriverAssigned Kind = "driver_assigned"
+	// TripReminder is sent ahead of the trip by the reminders scheduler.
+	TripReminder Kind = "trip_reminder"
+)
+
+// Data is what the templates render.
+type Data struct {
+	Booking    domain.Booking
+	ManageURL  string
+	DriverName string
+}
+
+// statusKinds is the rider email sent when a booking moves to a status.
+var statusKinds = map[domain.BookingStatus]Kind{
+	domain.BookingConfirmed: BookingConfirmed,
+	domain.BookingCanceled:  BookingCanceled,
+}
+
+type Notifier struct {
+	// BaseURL is the frontend origin manage links point at.
+	BaseURL string
+	// EmailOnly sends reminders by email even to riders who chose texts,
+	// for deployments that cannot send them.
+	EmailOnly bool
+}
+
+func New(baseURL string) *Notifier {
+	return &Notifier{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// ManageLink is the frontend page where the holder of the booking's manage
+// token can view, edit or cancel it without logging in.
+func (n *Notifier) ManageLink(b domain.Booking) string {
+	return fmt.Sprintf("%s/bookings/%d/manage?manage_token=%s", n.BaseURL, b.ID, url.QueryEscape(b.ManageToken))
+}
+
+// BookingEmails renders the emails e sends: one to the rider for the events
+// they are told about, plus one to the driver on assignment. Other events
+// send nothing.
+func (n *Notifier) BookingEmails(e domain.BookingEvent, b domain.Booking, driver *domain.Recipient) ([]domain.OutboxMessage, error) {
+	var kind Kind
+	switch e.Type {
+	case domain.EventCreated:
+		kind = BookingCreated
+	case domain.EventUpdated:
+		kind = BookingUpdated
+	case domain.EventAssigned:
+		kind = BookingAssigned
+	case domain.EventStatusChanged:
+		if e.ToStatus != nil {
+			kind = statusKinds[*e.ToStatus]
+		}
+	}
+	if kind == "" {
+		return nil, nil
+	}
+
+	d := Data{Booking: b}
+	if driver != nil {
+		d.DriverName = driver.Name
+	}
+	out := make([]domain.OutboxMessage, 0, 2)
+	m, err := n.message(kind, d, domain.Recipient{Email: b.RiderEmail, Name: b.RiderName, Locale: b.Locale})
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, m)
+
+	if kind == BookingAssigned && driver != nil {
+		m, err := n.message(DriverAssigned, d, *driver)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func (n *Notifier) message(kind Kind, d Data, to domain.Recipient) (domain.OutboxMessage, error) {
+	e, err := n.Render(kind, to.Locale, d)
+	if err != nil {
+		return domain.OutboxMessage{}, err
+	}
+	return domain.OutboxMessage{Channel: domain.ChannelEmail, ToEmail: to.Email, ToName: to.Name, Subject: e.Subject, Text: e.Text, HTML: e.HTML}, nil
+}
+
+// Render renders the email for kind in locale. ManageURL is derived from the
+// booking when unset.
+func (n *Notifier) Render(kind Kind, locale string, d Data) (templates.Email, error) {
+	if d.ManageURL == "" {
+		d.ManageURL = n.ManageLink(d.Booking)
+	}
+	return templates.Render(locale, string(kind), d)
+}
+
+// TripReminders renders the message reminding the rider of their trip, by
+// email or text as they chose when booking.
+func (n *Notifier) TripReminders(b domain.Booking) ([]domain.OutboxMessage, error) {
+	if b.ReminderChannel == domain.ChannelSMS && !n.EmailOnly {
+		body, err := n.TripReminderSMS(b)
+		if err != nil {
+			return nil, err
+		}
+		return []domain.OutboxMessage{{Channel: domain.ChannelSMS, ToPhone: b.RiderPhone, Text: body}}, nil
+	}
+	m, err := n.message(TripReminder, Data{Booking: b}, domain.Recipient{Email: b.RiderEmail, Name: b.RiderName, Locale: b.Locale})
+	if err != nil {
+		return nil, err
+	}
+	return []domain.OutboxMessage{m}, nil
+}
+
+// TripReminderSMS renders the text reminding the rider of their trip.
+func (n *Notifier) TripReminderSMS(b domain.Booking) (string, error) {
+	return templates.RenderSMS(b.Locale, "trip_reminder", Data{Booking: b, ManageURL: n.ManageLink(b)})
+}
@@ -0,0 +1,104 @@
+package notify_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/notify"
+)
+
+func TestRender(t *testing.T) {
+	n := notify.New("https://app.example.com/")
+	b := domain.Booking{
+		ID: 42, ManageToken: "tok-123", RiderName: "Ana <script>",
+		Pickup: "SFO", Dropoff: "Union Square",
+		ScheduledAt: time.Date(2030, 3, 12, 12, 0, 0, 0, time.UTC),
+		Passengers:  2,
+	}
+
+	for _, kind := range []notify.Kind{notify.BookingCreated, notify.BookingUpdated, notify.BookingConfirmed, notify.BookingAssigned, notify.BookingCanceled} {
+		e, err := n.Render(kind, "en", notify.Data{Booking: b, DriverName: "Sam"})
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if !strings.Contains(e.Subject, "LuxSuv") {
+			t.Errorf("%s: subject %q", kind, e.Subject)
+		}
+		link := "https://app.example.com/bookings/42/manage?manage_token=tok-123"
+		if !strings.Contains(e.Text, link) || !strings.Contains(e.HTML, link) {
+			t.Errorf("%s: manage link missing", kind)
+		}
+		if strings.Contains(e.HTML, "<script>") {
+			t.Errorf("%s: rider name not escaped in html", kind)
+		}
+	}
+}
+
+func TestBookingEmails(t *testing.T) {
+	n := notify.New("https://app.example.com")
+	b := domain.Booking{ID: 7, ManageToken: "tok", RiderEmail: "ana@example.com", RiderName: "Ana", Locale: "es"}
+	status := func(s domain.BookingStatus) *domain.BookingStatus { return &s }
+	driver := &domain.Recipient{Email: "sam@example.com", Name: "Sam", Locale: "en"}
+
+	tests := []struct {
+		name   string
+		ev     domain.BookingEvent
+		driver *domain.Recipient
+		to     []string
+	}{
+		{"created", domain.BookingEvent{Type: domain.EventCreated}, nil, []string{"ana@example.com"}},
+		{"confirmed", domain.BookingEvent{Type: domain.EventStatusChanged, ToStatus: status(domain.BookingConfirmed)}, nil, []string{"ana@example.com"}},
+		{"completed", domain.BookingEvent{Type: domain.EventStatusChanged, ToStatus: status(domain.BookingCompleted)}, nil, nil},
+		{"assigned", domain.BookingEvent{Type: domain.EventAssigned}, driver, []string{"ana@example.com", "sam@example.com"}},
+		{"unassigned", domain.BookingEvent{Type: domain.EventUnassigned}, nil, nil},
+	}
+	for _, tt := range tests {
+		msgs, err := n.BookingEmails(tt.ev, b, tt.driver)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(msgs) != len(tt.to) {
+			t.Fatalf("%s: got %d emails, want %d", tt.name, len(msgs), len(tt.to))
+		}
+		for i, m := range msgs {
+			if m.ToEmail != tt.to[i] {
+				t.Errorf("%s: email %d to %q, want %q", tt.name, i, m.ToEmail, tt.to[i])
+			}
+		}
+		if tt.name == "assigned" {
+			if !strings.Contains(msgs[0].Subject, "conductor") || !strings.Contains(msgs[1].Subject, "New LuxSuv ride") {
+				t.Errorf("emails not in the recipients' locales: %q, %q", msgs[0].Subject, msgs[1].Subject)
+			}
+			if strings.Contains(msgs[1].Text, "manage_token") {
+				t.Errorf("driver email leaks the rider's manage link")
+			}
+		}
+	}
+}
+
+func TestTripReminderSMS(t *testing.T) {
+	n := notify.New("https://app.example.com")
+	b := domain.Booking{ID: 7, ManageToken: "tok", Pickup: "SFO", Dropoff: "Union Square", Locale: "es"}
+	body, err := n.TripReminderSMS(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, "Recordatorio") || !strings.Contains(body, "/bookings/7/manage?manage_token=tok") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestTripReminders_EmailOnly(t *testing.T) {
+	n := notify.New("https://app.example.com")
+	n.EmailOnly = true
+	b := domain.Booking{ID: 7, ManageToken: "tok", RiderEmail: "rider@example.com", RiderPhone: "+15555550100", ReminderChannel: domain.ChannelSMS}
+	msgs, err := n.TripReminders(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Channel != domain.ChannelEmail || msgs[0].ToEmail != "rider@example.com" {
+		t.Errorf("messages = %+v, want one email", msgs)
+	}
+}
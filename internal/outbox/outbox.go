@@ -0,0 +1,155 @@
+// Package outbox delivers the emails and texts queued in the email_outbox
+// table. Producers write messages alongside the change they report; a Worker
+// sends them later, retrying with exponential backoff and dead-lettering
+// messages that keep failing.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/mailer"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/sms"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+	"github.com/diagnosis/luxsuv-bookings/internal/templates"
+)
+
+// Policy decides when a failed message is retried and when it is given up on.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultPolicy = Policy{MaxAttempts: 8, BaseDelay: 30 * time.Second, MaxDelay: time.Hour}
+
+// Backoff is the wait after the given failed attempt (1-based): BaseDelay,
+// doubled for each further attempt, capped at MaxDelay.
+func (p Policy) Backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+// Email renders the named email from the templates package in locale as a
+// message to toEmail, ready to be queued with the change it reports.
+func Email(toEmail, toName, locale, name string, data any) (domain.OutboxMessage, error) {
+	e, err := templates.Render(locale, name, data)
+	if err != nil {
+		return domain.OutboxMessage{}, err
+	}
+	return domain.OutboxMessage{Channel: domain.ChannelEmail, ToEmail: toEmail, ToName: toName, Subject: e.Subject, Text: e.Text, HTML: e.HTML}, nil
+}
+
+// SMS renders the named text from the templates package in locale as a
+// message to toPhone.
+func SMS(toPhone, locale, name string, data any) (domain.OutboxMessage, error) {
+	body, err := templates.RenderSMS(locale, name, data)
+	if err != nil {
+		return domain.OutboxMessage{}, err
+	}
+	return domain.OutboxMessage{Channel: domain.ChannelSMS, ToPhone: toPhone, Text: body}, nil
+}
+
+// Worker sends due outbox messages through Mail, or SMS for texts.
+type Worker struct {
+	Repo   postgres.OutboxRepo
+	Mail   mailer.Service
+	SMS    sms.Sender
+	Policy Policy
+	// Interval is how often the outbox is polled.
+	Interval time.Duration
+	// Batch is how many messages are claimed per poll.
+	Batch int
+	// Lease is how long a message is hidden from other workers while it is
+	// being sent. It is taken again right before each send, so a slow batch
+	// cannot outlive it.
+	Lease time.Duration
+}
+
+func NewWorker(repo postgres.OutboxRepo, mail mailer.Service, sender sms.Sender) *Worker {
+	return &Worker{
+		Repo:     repo,
+		Mail:     mail,
+		SMS:      sender,
+		Policy:   DefaultPolicy,
+		Interval: 5 * time.Second,
+		Batch:    20,
+		Lease:    2 * time.Minute,
+	}
+}
+
+// Run polls the outbox until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	t := time.NewTicker(w.Interval)
+	defer t.Stop()
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			log.Printf("outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce claims one batch of due messages and tries to send each, returning
+// how many were sent.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	msgs, err := w.Repo.Claim(ctx, w.Batch, w.Lease)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, m := range msgs {
+		// The claim's lease may have run out while earlier messages were sent;
+		// if another worker has claimed this one since, leave it to them.
+		ok, err := w.Repo.Renew(ctx, m.ID, m.Attempts, w.Lease)
+		if err != nil {
+			log.Printf("outbox: renew lease on message %d: %v", m.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := w.deliver(m); err != nil {
+			dead := m.Attempts >= w.Policy.MaxAttempts
+			next := time.Now().Add(w.Policy.Backoff(m.Attempts))
+			if dead {
+				log.Printf("outbox: giving up on message %d to %s after %d attempts: %v", m.ID, m.To(), m.Attempts, err)
+			} else {
+				log.Printf("outbox: message %d to %s failed (attempt %d), retrying at %s: %v", m.ID, m.To(), m.Attempts, next.Format(time.RFC3339), err)
+			}
+			if err := w.Repo.MarkFailed(ctx, m.ID, err.Error(), next, dead); err != nil {
+				log.Printf("outbox: record failure of message %d: %v", m.ID, err)
+			}
+			continue
+		}
+		if err := w.Repo.MarkSent(ctx, m.ID); err != nil {
+			// The lease will expire and the message go out again; better twice than never.
+			log.Printf("outbox: mark message %d sent: %v", m.ID, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (w *Worker) deliver(m domain.OutboxMessage) error {
+	if m.Channel == domain.ChannelSMS {
+		if w.SMS == nil {
+			return errors.New("no SMS sender configured")
+		}
+		_, err := w.SMS.Send(m.ToPhone, m.Text)
+		return err
+	}
+	_, err := w.Mail.Send(m.ToEmail, m.ToName, m.Subject, m.Text, m.HTML)
+	return err
+}
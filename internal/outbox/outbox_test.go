@@ -0,0 +1,108 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/outbox"
+)
+
+func TestBackoff(t *testing.T) {
+	p := outbox.Policy{MaxAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute}
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+type fakeRepo struct {
+	due    []domain.OutboxMessage
+	taken  map[int64]bool // claimed by another worker since
+	sent   []int64
+	failed map[int64]bool // id -> dead
+}
+
+func (f *fakeRepo) Enqueue(ctx context.Context, m *domain.OutboxMessage) error { return nil }
+
+func (f *fakeRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	out := f.due
+	f.due = nil
+	for i := range out {
+		out[i].Attempts++
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) Renew(ctx context.Context, id int64, attempts int, lease time.Duration) (bool, error) {
+	return !f.taken[id], nil
+}
+
+func (f *fakeRepo) Purge(ctx context.Context, olderThan time.Duration) (int64, error) { return 0, nil }
+
+func (f *fakeRepo) MarkSent(ctx context.Context, id int64) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeRepo) MarkFailed(ctx context.Context, id int64, errMsg string, next time.Time, dead bool) error {
+	f.failed[id] = dead
+	return nil
+}
+
+type fakeMailer struct{ down map[string]bool }
+
+func (f *fakeMailer) Send(toEmail, toName, subject, text, html string) (string, error) {
+	if f.down[toEmail] {
+		return "", errors.New("connection refused")
+	}
+	return "ok", nil
+}
+
+type fakeSMS struct{ to []string }
+
+func (f *fakeSMS) Send(toPhone, body string) (string, error) {
+	f.to = append(f.to, toPhone)
+	return "ok", nil
+}
+
+func TestRunOnce(t *testing.T) {
+	repo := &fakeRepo{
+		due: []domain.OutboxMessage{
+			{ID: 1, ToEmail: "ok@example.com"},
+			{ID: 2, ToEmail: "down@example.com", Attempts: 1},
+			{ID: 3, ToEmail: "down@example.com", Attempts: 2},
+			{ID: 4, Channel: domain.ChannelSMS, ToEmail: "down@example.com", ToPhone: "+14155550100"},
+			{ID: 5, ToEmail: "ok@example.com"},
+		},
+		taken:  map[int64]bool{5: true},
+		failed: map[int64]bool{},
+	}
+	texts := &fakeSMS{}
+	w := outbox.NewWorker(repo, &fakeMailer{down: map[string]bool{"down@example.com": true}}, texts)
+	w.Policy.MaxAttempts = 3
+
+	sent, err := w.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 2 || len(repo.sent) != 2 || repo.sent[0] != 1 || repo.sent[1] != 4 {
+		t.Errorf("sent = %d %v, want messages 1 and 4", sent, repo.sent)
+	}
+	if len(texts.to) != 1 || texts.to[0] != "+14155550100" {
+		t.Errorf("texts = %v, want message 4 texted rather than emailed", texts.to)
+	}
+	if dead, ok := repo.failed[2]; !ok || dead {
+		t.Errorf("message 2: failed=%v dead=%v, want a retry", ok, dead)
+	}
+	if dead, ok := repo.failed[3]; !ok || !dead {
+		t.Errorf("message 3: failed=%v dead=%v, want dead-lettered", ok, dead)
+	}
+	if _, ok := repo.failed[5]; ok {
+		t.Errorf("message 5 was attempted after another worker claimed it")
+	}
+}
@@ -2,12 +2,16 @@ package auth
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Audience is the aud claim of every token this API issues and accepts.
+const Audience = "luxsuv-api"
+
 type Claims struct {
 	Sub   int64  `json:"sub"`
 	Email string `json:"email"`
@@ -16,14 +20,53 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func secret() []byte {
-	if s := os.Getenv("JWT_SECRET"); s != "" {
-		return []byte(s)
+var (
+	mu     sync.RWMutex
+	keys   *KeySet
+	issuer = "luxsuv-api"
+)
+
+// Configure sets the keys tokens are signed and verified with, and the issuer
+// written to and required in the iss claim. Call it once at startup.
+func Configure(ks *KeySet, iss string) {
+	mu.Lock()
+	defer mu.Unlock()
+	keys = ks
+	if iss != "" {
+		issuer = iss
 	}
-	return []byte("dev-only-secret-change-in-prod")
+}
+
+// CurrentKeySet returns the configured keys, generating an ephemeral set if
+// Configure was never called (tests, quick local runs).
+func CurrentKeySet() *KeySet {
+	mu.RLock()
+	ks := keys
+	mu.RUnlock()
+	if ks != nil {
+		return ks
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if keys == nil {
+		eph, err := NewEphemeralKeySet()
+		if err != nil {
+			panic(fmt.Sprintf("auth: generate ephemeral key: %v", err))
+		}
+		keys = eph
+	}
+	return keys
+}
+
+func currentIssuer() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return issuer
 }
 
 func NewAccessToken(sub int64, email, role, score string, ttl time.Duration) (string, error) {
+	ks := CurrentKeySet()
 	now := time.Now()
 	claims := Claims{
 		Sub:   sub,
@@ -31,19 +74,38 @@ func NewAccessToken(sub int64, email, role, score string, ttl time.Duration) (st
 		Role:  role,
 		Scope: score,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    currentIssuer(),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
-			Audience:  []string{"luxsuv-api"},
+			Audience:  []string{Audience},
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret())
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.Private)
 }
+
+// Parse verifies a token against the key named by its kid header. The token's
+// alg must be the one that key is registered for, and iss/aud/exp are required.
 func Parse(tokenString string) (*Claims, error) {
+	ks := CurrentKeySet()
 	tok, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return secret(), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		k, ok := ks.byID[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if token.Method.Alg() != k.Method.Alg() {
+			return nil, fmt.Errorf("alg %s not allowed for kid %q", token.Method.Alg(), kid)
+		}
+		return k.Public, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithAudience(Audience),
+		jwt.WithIssuer(currentIssuer()),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
 		return nil, err
 	}
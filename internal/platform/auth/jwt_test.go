@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
+)
+
+func writeKey(t *testing.T, dir, kid string, private bool) ed25519.PrivateKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if private {
+		der, _ := x509.MarshalPKCS8PrivateKey(priv)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	} else {
+		der, _ := x509.MarshalPKIXPublicKey(pub)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestKeySet_SignAndVerifyWithRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldPriv := writeKey(t, dir, "2025-01", true)
+	writeKey(t, dir, "2025-06", true)
+
+	// Sign with the old key first, as if issued before rotation.
+	ks, err := auth.LoadKeySet(dir, "2025-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Configure(ks, "luxsuv-test")
+	oldTok, err := auth.NewAccessToken(7, "a@example.com", auth.RoleRider, auth.ScopeForRole(auth.RoleRider), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate: the old key is now published as public-only.
+	der, _ := x509.MarshalPKIXPublicKey(oldPriv.Public())
+	_ = os.WriteFile(filepath.Join(dir, "2025-01.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+	ks, err = auth.LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Configure(ks, "luxsuv-test")
+
+	claims, err := auth.Parse(oldTok)
+	if err != nil {
+		t.Fatalf("token signed with rotated-out key should still verify: %v", err)
+	}
+	if claims.Sub != 7 || !claims.HasScope(auth.ScopeBookingsReadSelf) {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	newTok, _ := auth.NewAccessToken(7, "a@example.com", auth.RoleRider, "", time.Minute)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newTok, &auth.Claims{})
+	if parsed.Header["kid"] != "2025-06" || parsed.Method.Alg() != "EdDSA" {
+		t.Fatalf("expected EdDSA token signed by kid 2025-06, got %v", parsed.Header)
+	}
+
+	if n := len(ks.JWKS().Keys); n != 2 {
+		t.Fatalf("expected 2 keys in JWKS, got %d", n)
+	}
+}
+
+func TestParse_RejectsForeignTokens(t *testing.T) {
+	dir := t.TempDir()
+	mainKey := writeKey(t, dir, "main", true)
+	ks, err := auth.LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Configure(ks, "luxsuv-test")
+
+	base := auth.Claims{
+		Sub: 1, Role: auth.RoleAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "luxsuv-test",
+			Audience:  []string{auth.Audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, base)
+	hs.Header["kid"] = "main"
+	hsTok, _ := hs.SignedString([]byte("dev-only-secret-change-in-prod"))
+
+	_, strangerKey, _ := ed25519.GenerateKey(rand.Reader)
+	unknown := jwt.NewWithClaims(jwt.SigningMethodEdDSA, base)
+	unknown.Header["kid"] = "other"
+	unknownTok, _ := unknown.SignedString(strangerKey)
+
+	wrongAud := base
+	wrongAud.Audience = []string{"someone-else"}
+	wrongIss := base
+	wrongIss.Issuer = "evil"
+
+	sign := func(c auth.Claims, key ed25519.PrivateKey) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+		tok.Header["kid"] = "main"
+		s, _ := tok.SignedString(key)
+		return s
+	}
+
+	if _, err := auth.Parse(sign(base, mainKey)); err != nil {
+		t.Fatalf("control token rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"hs256 with old dev secret", hsTok},
+		{"unknown kid", unknownTok},
+		{"forged signature", sign(base, strangerKey)},
+		{"wrong audience", sign(wrongAud, mainKey)},
+		{"wrong issuer", sign(wrongIss, mainKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := auth.Parse(tt.token); err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is one JWT key. Private is nil for verification-only (retired or
+// partner-published) keys.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Public  crypto.PublicKey
+	Private crypto.Signer
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens may be verified with.
+type KeySet struct {
+	signing *Key
+	byID    map[string]*Key
+}
+
+// LoadKeySet reads every *.pem file in dir as a key whose kid is the file name
+// without extension. Private keys (PKCS#8 or PKCS#1, Ed25519 or RSA) can sign
+// and verify; public keys (PKIX) only verify, which is how a rotated-out key
+// stays valid until its tokens expire. signingKID picks the signing key; when
+// empty, the last private key in kid order is used.
+func LoadKeySet(dir, signingKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	ks := &KeySet{byID: map[string]*Key{}}
+	for _, p := range paths {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(p), ".pem")
+		k, err := parseKeyPEM(kid, raw)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", p, err)
+		}
+		ks.byID[kid] = k
+		if k.Private != nil && (signingKID == "" || signingKID == kid) {
+			ks.signing = k
+		}
+	}
+	if ks.signing == nil {
+		if signingKID != "" {
+			return nil, fmt.Errorf("jwt signing key %q not found in %s", signingKID, dir)
+		}
+		return nil, fmt.Errorf("no private jwt key found in %s", dir)
+	}
+	return ks, nil
+}
+
+// NewEphemeralKeySet returns a key set with a freshly generated Ed25519 key.
+// Tokens it signs stop verifying when the process exits; meant for development and tests.
+func NewEphemeralKeySet() (*KeySet, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{ID: "ephemeral", Method: jwt.SigningMethodEdDSA, Public: pub, Private: priv}
+	return &KeySet{signing: k, byID: map[string]*Key{k.ID: k}}, nil
+}
+
+func parseKeyPEM(kid string, raw []byte) (*Key, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Key{ID: kid}
+	switch v := parsed.(type) {
+	case ed25519.PrivateKey:
+		k.Method, k.Public, k.Private = jwt.SigningMethodEdDSA, v.Public(), v
+	case *rsa.PrivateKey:
+		k.Method, k.Public, k.Private = jwt.SigningMethodRS256, &v.PublicKey, v
+	case ed25519.PublicKey:
+		k.Method, k.Public = jwt.SigningMethodEdDSA, v
+	case *rsa.PublicKey:
+		k.Method, k.Public = jwt.SigningMethodRS256, v
+	default:
+		return nil, fmt.Errorf("unsupported key type %T (want Ed25519 or RSA)", parsed)
+	}
+	return k, nil
+}
+
+// JWK is the public half of a key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every verification key, sorted by kid.
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: make([]JWK, 0, len(ks.byID))}
+	for _, k := range ks.byID {
+		j := JWK{Kid: k.ID, Alg: k.Method.Alg(), Use: "sig"}
+		switch pub := k.Public.(type) {
+		case ed25519.PublicKey:
+			j.Kty, j.Crv, j.X = "OKP", "Ed25519", base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			j.Kty = "RSA"
+			j.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			j.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		out.Keys = append(out.Keys, j)
+	}
+	sort.Slice(out.Keys, func(i, j int) bool { return out.Keys[i].Kid < out.Keys[j].Kid })
+	return out
+}
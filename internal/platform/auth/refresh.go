@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// NewRefreshToken returns a random opaque refresh token and the hash to store for it.
+func NewRefreshToken() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, HashToken(raw), nil
+}
+
+// HashToken hashes a raw refresh or single-use link token for storage and
+// lookup, so a database leak does not expose live tokens.
+func HashToken(raw string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(raw)))
+}
@@ -0,0 +1,9 @@
+package auth
+
+// Roles stored in users.role and carried in Claims.Role. Guest sessions use RoleGuest.
+const (
+	RoleRider  = "rider"
+	RoleDriver = "driver"
+	RoleAdmin  = "admin"
+	RoleGuest  = "guest"
+)
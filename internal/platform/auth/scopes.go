@@ -0,0 +1,46 @@
+package auth
+
+import "strings"
+
+// Scopes granted in access tokens. Guest sessions carry their own scopes (see NewGuestSession).
+const (
+	ScopeBookingsReadSelf  = "bookings.read:self"
+	ScopeBookingsWriteSelf = "bookings.write:self"
+	ScopeBookingsReadAll   = "bookings.read:all"
+	ScopeBookingsWriteAll  = "bookings.write:all"
+	ScopeTripsReadSelf     = "trips.read:self"
+	ScopeTripsWriteSelf    = "trips.write:self"
+	ScopeFleetReadAll      = "fleet.read:all"
+	ScopeFleetWriteAll     = "fleet.write:all"
+	ScopeJobsReadAll       = "jobs.read:all"
+)
+
+// ScopeForRole returns the space-separated scope string issued to a user with the given role.
+func ScopeForRole(role string) string {
+	switch role {
+	case RoleAdmin:
+		return strings.Join([]string{ScopeBookingsReadAll, ScopeBookingsWriteAll, ScopeFleetReadAll, ScopeFleetWriteAll, ScopeJobsReadAll}, " ")
+	case RoleDriver:
+		return strings.Join([]string{ScopeTripsReadSelf, ScopeTripsWriteSelf}, " ")
+	case RoleRider:
+		return strings.Join([]string{ScopeBookingsReadSelf, ScopeBookingsWriteSelf}, " ")
+	default:
+		return ""
+	}
+}
+
+// Scopes splits the scope claim. Both space- and comma-separated lists are
+// accepted, since older tokens were issued with commas.
+func (c *Claims) Scopes() []string {
+	return strings.FieldsFunc(c.Scope, func(r rune) bool { return r == ' ' || r == ',' })
+}
+
+// HasScope reports whether the token grants scope s.
+func (c *Claims) HasScope(s string) bool {
+	for _, sc := range c.Scopes() {
+		if sc == s {
+			return true
+		}
+	}
+	return false
+}
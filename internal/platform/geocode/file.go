@@ -0,0 +1,62 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+)
+
+// File is an offline Geocoder over a fixed list of places, for tests and local
+// development. Lookups ignore case and repeated whitespace.
+type File struct {
+	places map[string]domain.Location
+}
+
+// filePlace is one entry of a places file. Besides its formatted address, a
+// place is found by any of its aliases.
+type filePlace struct {
+	domain.Location
+	Aliases []string `json:"aliases"`
+}
+
+// LoadFile reads a JSON array of places, e.g.
+//
+//	[{"formatted_address": "San Francisco International Airport, CA 94128, USA",
+//	  "lat": 37.6213, "lng": -122.379, "place_id": "sfo", "aliases": ["SFO"]}]
+func LoadFile(path string) (*File, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("geocode: read %s: %w", path, err)
+	}
+	var places []filePlace
+	if err := json.Unmarshal(raw, &places); err != nil {
+		return nil, fmt.Errorf("geocode: parse %s: %w", path, err)
+	}
+
+	f := &File{places: make(map[string]domain.Location)}
+	for _, p := range places {
+		if err := p.Location.Validate(); err != nil {
+			return nil, fmt.Errorf("geocode: %s: %q: %w", path, p.FormattedAddress, err)
+		}
+		for _, key := range append([]string{p.FormattedAddress}, p.Aliases...) {
+			f.places[fileKey(key)] = p.Location
+		}
+	}
+	return f, nil
+}
+
+func (f *File) Geocode(_ context.Context, address string) (*domain.Location, error) {
+	loc, ok := f.places[fileKey(address)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &loc, nil
+}
+
+func fileKey(s string) string { return strings.ToLower(strings.Join(strings.Fields(s), " ")) }
+
+var _ Geocoder = (*File)(nil)
@@ -0,0 +1,86 @@
+// Package geocode resolves free-text pickup and dropoff addresses to locations.
+package geocode
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+)
+
+// ErrNotFound is returned by a Geocoder that cannot resolve an address.
+var ErrNotFound = errors.New("geocode: address not found")
+
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (*domain.Location, error)
+}
+
+// Nop resolves nothing. It is used when no geocoding provider is configured.
+type Nop struct{}
+
+func (Nop) Geocode(context.Context, string) (*domain.Location, error) { return nil, ErrNotFound }
+
+// Resolve returns the location to store for address. The address is geocoded
+// first; a client-supplied location (e.g. from an address autocomplete) is
+// validated and used only when the geocoder has no answer. Lookup failures are
+// logged and leave the location empty, so a geocoder outage never blocks a
+// booking.
+func Resolve(ctx context.Context, g Geocoder, address string, given *domain.Location) (*domain.Location, error) {
+	if given != nil {
+		if err := given.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	loc, err := g.Geocode(ctx, address)
+	if err == nil {
+		return loc, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		log.Printf("geocode: %q: %v", address, err)
+	}
+	if given != nil && given.FormattedAddress == "" {
+		given.FormattedAddress = address
+	}
+	return given, nil
+}
+
+// ResolveTrip fills in the pickup and dropoff locations of a new booking.
+// Client-supplied locations are dropped unless trustClient is set, so where
+// they matter (a service area is enforced) only server-geocoded coordinates
+// count.
+func ResolveTrip(ctx context.Context, g Geocoder, in *domain.BookingGuestReq, trustClient bool) error {
+	if !trustClient {
+		in.PickupLocation, in.DropoffLocation = nil, nil
+	}
+	var err error
+	if in.PickupLocation, err = Resolve(ctx, g, in.Pickup, in.PickupLocation); err != nil {
+		return err
+	}
+	in.DropoffLocation, err = Resolve(ctx, g, in.Dropoff, in.DropoffLocation)
+	return err
+}
+
+// ResolvePatch fills in the locations for the pickup and dropoff a patch changes.
+// A location sent without its address is rejected with domain.ErrInvalidLocation;
+// trustClient works as in ResolveTrip.
+func ResolvePatch(ctx context.Context, g Geocoder, p *domain.GuestPatch, trustClient bool) error {
+	if (p.PickupLocation != nil && p.Pickup == nil) || (p.DropoffLocation != nil && p.Dropoff == nil) {
+		return domain.ErrInvalidLocation
+	}
+	if !trustClient {
+		p.PickupLocation, p.DropoffLocation = nil, nil
+	}
+	var err error
+	if p.Pickup != nil {
+		if p.PickupLocation, err = Resolve(ctx, g, *p.Pickup, p.PickupLocation); err != nil {
+			return err
+		}
+	}
+	if p.Dropoff != nil {
+		if p.DropoffLocation, err = Resolve(ctx, g, *p.Dropoff, p.DropoffLocation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package geocode_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/geocode"
+)
+
+func TestFile(t *testing.T) {
+	g, err := geocode.LoadFile("testdata/places.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	sfo, err := g.Geocode(ctx, "  sfo   airport ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sfo.PlaceID != "test-sfo" {
+		t.Fatalf("place_id = %q, want test-sfo", sfo.PlaceID)
+	}
+	if _, err := g.Geocode(ctx, "Nowhere"); !errors.Is(err, geocode.ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+
+	union, _ := g.Geocode(ctx, "union square")
+	if d := domain.DistanceMiles(*sfo, *union); math.Abs(d-11.7) > 0.5 {
+		t.Fatalf("distance = %.2f mi, want about 11.7", d)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+
+	loc, err := geocode.Resolve(ctx, geocode.Nop{}, "1 Main St", nil)
+	if err != nil || loc != nil {
+		t.Fatalf("unknown address: got %v, %v; want nil, nil", loc, err)
+	}
+
+	loc, err = geocode.Resolve(ctx, geocode.Nop{}, "1 Main St", &domain.Location{Lat: 37.7, Lng: -122.4})
+	if err != nil || loc.FormattedAddress != "1 Main St" {
+		t.Fatalf("given location: got %+v, %v", loc, err)
+	}
+
+	if _, err := geocode.Resolve(ctx, geocode.Nop{}, "x", &domain.Location{Lat: 91}); !errors.Is(err, domain.ErrInvalidLocation) {
+		t.Fatalf("err = %v, want ErrInvalidLocation", err)
+	}
+
+	// The geocoder's answer beats whatever the client claims.
+	g, err := geocode.LoadFile("testdata/places.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err = geocode.Resolve(ctx, g, "SFO Airport", &domain.Location{Lat: 10, Lng: 10})
+	if err != nil || loc.PlaceID != "test-sfo" {
+		t.Fatalf("geocoded address: got %+v, %v; want the places file entry", loc, err)
+	}
+}
@@ -1,6 +1,7 @@
 package mailer
 
+// Service sends one rendered email. Bodies are rendered by the templates
+// package; implementations only deliver them.
 type Service interface {
 	Send(toEmail, toName, subject, text, html string) (string, error)
-	SendGuestAccess(email, code, link string) error
 }
@@ -64,11 +64,3 @@ func (m *Mailer) Send(toEmail, toName, subject, text, html string) (string, erro
 	// MailerSend uses X-Message-Id
 	return res.Header.Get("X-Message-Id"), nil
 }
-
-func (m *Mailer) SendGuestAccess(email, code, link string) error {
-	subject := "Your LuxSuv booking access link"
-	text := fmt.Sprintf("Your code is %s. Or click the link: %s", code, link)
-	html := fmt.Sprintf(`<p>Your code is <b>%s</b></p><p>Or click <a href="%s">%s</a></p>`, code, link, link)
-	_, err := m.Send(email, "", subject, text, html)
-	return err
-}
@@ -110,13 +110,3 @@ func (s *SMTPMailer) Send(toEmail, toName, subject, text, html string) (string,
 
 	return "", fmt.Errorf("smtp send failed")
 }
-
-func (s *SMTPMailer) SendGuestAccess(email, code, link string) error {
-	subject := "Your LuxSuv guest access code"
-	text := fmt.Sprintf("Your access code is %s\nOr click the magic link: %s", code, link)
-	html := fmt.Sprintf(`<p>Your access code is <b>%s</b></p>
-        <p>Or click <a href="%s">this link</a> to sign in directly.</p>`, code, link)
-
-	_, err := s.Send(email, email, subject, text, html)
-	return err
-}
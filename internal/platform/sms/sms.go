@@ -0,0 +1,87 @@
+// Package sms sends text messages to riders, as an alternative to email.
+package sms
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Sender interface {
+	// Send texts body to an E.164 phone number and returns the provider's message ID.
+	Send(toPhone, body string) (string, error)
+}
+
+// Log is a Sender for development and tests that delivers nothing. Each message
+// is appended as a JSON line to the file at Path, or written to the standard
+// logger when Path is empty.
+type Log struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewLog(path string) *Log { return &Log{Path: path} }
+
+// Message is one line of a Log file.
+type Message struct {
+	ID     string    `json:"id"`
+	To     string    `json:"to"`
+	Body   string    `json:"body"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+func (l *Log) Send(toPhone, body string) (string, error) {
+	toPhone = strings.TrimSpace(toPhone)
+	if toPhone == "" {
+		return "", errors.New("sms: empty recipient phone")
+	}
+	m := Message{ID: uuid.NewString(), To: toPhone, Body: body, SentAt: time.Now().UTC()}
+	if l.Path == "" {
+		log.Printf("sms: to=%s id=%s body=%q", m.To, m.ID, m.Body)
+		return m.ID, nil
+	}
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return "", err
+	}
+	return m.ID, f.Close()
+}
+
+// ReadLog returns the messages in a Log file, oldest first.
+func ReadLog(path string) ([]Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []Message
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var m Message
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+var _ Sender = (*Log)(nil)
@@ -0,0 +1,33 @@
+package sms_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/sms"
+)
+
+func TestLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms.jsonl")
+	s := sms.NewLog(path)
+
+	for _, body := range []string{"first", "second"} {
+		if _, err := s.Send("+14155550100", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Send(" ", "nobody"); err == nil {
+		t.Error("sent to an empty phone")
+	}
+
+	msgs, err := sms.ReadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Body != "first" || msgs[1].Body != "second" || msgs[1].To != "+14155550100" {
+		t.Fatalf("log = %+v", msgs)
+	}
+	if msgs[0].ID == "" || msgs[0].ID == msgs[1].ID {
+		t.Errorf("message IDs not unique: %q %q", msgs[0].ID, msgs[1].ID)
+	}
+}
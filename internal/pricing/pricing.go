@@ -0,0 +1,217 @@
+// Package pricing turns a trip into an itemized fare using a rate card.
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+)
+
+// QuoteTTL is how long a quote can be attached to a new booking.
+const QuoteTTL = 30 * time.Minute
+
+// RateCard holds every price input. Money is in cents.
+type RateCard struct {
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	// Timezone is the IANA zone night hours and holidays are evaluated in.
+	Timezone string `json:"timezone"`
+
+	BaseFareCents    int64 `json:"base_fare_cents"`
+	PerMileCents     int64 `json:"per_mile_cents"`
+	PerMinuteCents   int64 `json:"per_minute_cents"`
+	MinimumFareCents int64 `json:"minimum_fare_cents"`
+
+	// MaxAverageMPH bounds how fast a per-ride trip is assumed to go, so a
+	// client's duration estimate is never below distance / MaxAverageMPH.
+	MaxAverageMPH float64 `json:"max_average_mph"`
+
+	HourlyRateCents    int64 `json:"hourly_rate_cents"`
+	HourlyMinimumHours int   `json:"hourly_minimum_hours"`
+
+	// ClassMultipliers scale the fare of vehicle classes priced differently
+	// from the card's rates; unlisted classes are charged the rates as is.
+	ClassMultipliers map[string]float64 `json:"class_multipliers"`
+
+	// AirportSurchargeCents is added once per trip end whose address
+	// contains one of AirportKeywords (case-insensitive).
+	AirportSurchargeCents int64    `json:"airport_surcharge_cents"`
+	AirportKeywords       []string `json:"airport_keywords"`
+
+	// Night runs from NightStartHour to NightEndHour local time and may wrap midnight.
+	NightMultiplier float64 `json:"night_multiplier"`
+	NightStartHour  int     `json:"night_start_hour"`
+	NightEndHour    int     `json:"night_end_hour"`
+
+	// Holidays are "2006-01-02" for one date or "01-02" for every year.
+	HolidayMultiplier float64  `json:"holiday_multiplier"`
+	Holidays          []string `json:"holidays"`
+}
+
+// DefaultRateCard is used when no rate card file is configured.
+func DefaultRateCard() *RateCard {
+	return &RateCard{
+		Name:                  "default",
+		Currency:              "USD",
+		Timezone:              "UTC",
+		BaseFareCents:         2500,
+		PerMileCents:          350,
+		PerMinuteCents:        75,
+		MinimumFareCents:      7500,
+		MaxAverageMPH:         60,
+		HourlyRateCents:       9500,
+		HourlyMinimumHours:    2,
+		AirportSurchargeCents: 1500,
+		AirportKeywords:       []string{"airport"},
+		NightMultiplier:       1.2,
+		NightStartHour:        22,
+		NightEndHour:          6,
+		HolidayMultiplier:     1.5,
+		Holidays:              []string{"01-01", "07-04", "12-25", "12-31"},
+	}
+}
+
+// LoadRateCard reads a rate card from a JSON file. Fields missing from the
+// file keep their DefaultRateCard values.
+func LoadRateCard(path string) (*RateCard, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := DefaultRateCard()
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, fmt.Errorf("rate card %s: %w", path, err)
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return nil, fmt.Errorf("rate card %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// MinTripMinutes is the least time a trip of miles can take at MaxAverageMPH.
+func (c *RateCard) MinTripMinutes(miles float64) int {
+	if c.MaxAverageMPH <= 0 {
+		return 0
+	}
+	return int(math.Ceil(miles / c.MaxAverageMPH * 60))
+}
+
+// Quote prices a trip. The result has no ID or expiry; the caller persists it.
+func (c *RateCard) Quote(in domain.QuoteReq) (*domain.Quote, error) {
+	if in.DistanceMiles < 0 || in.DurationMinutes < 0 {
+		return nil, fmt.Errorf("distance and duration must not be negative")
+	}
+
+	q := &domain.Quote{
+		RideType:        in.RideType,
+		VehicleClass:    in.VehicleClass,
+		Pickup:          in.Pickup,
+		Dropoff:         in.Dropoff,
+		ScheduledAt:     in.ScheduledAt,
+		DistanceMiles:   in.DistanceMiles,
+		DurationMinutes: in.DurationMinutes,
+		Currency:        c.Currency,
+		RateCard:        c.Name,
+	}
+	add := func(code, desc string, cents int64) {
+		if cents != 0 {
+			q.Items = append(q.Items, domain.QuoteItem{Code: code, Description: desc, AmountCents: cents})
+		}
+	}
+
+	var fare int64
+	switch in.RideType {
+	case domain.RidePerRide:
+		base := c.BaseFareCents
+		dist := int64(math.Round(in.DistanceMiles * float64(c.PerMileCents)))
+		mins := int64(in.DurationMinutes) * c.PerMinuteCents
+		add("base", "Base fare", base)
+		add("distance", fmt.Sprintf("%.1f mi", in.DistanceMiles), dist)
+		add("time", fmt.Sprintf("%d min", in.DurationMinutes), mins)
+		fare = base + dist + mins
+		if fare < c.MinimumFareCents {
+			add("minimum", "Minimum fare adjustment", c.MinimumFareCents-fare)
+			fare = c.MinimumFareCents
+		}
+	case domain.RideHourly:
+		hours := int(math.Ceil(float64(in.DurationMinutes) / 60))
+		if hours < c.HourlyMinimumHours {
+			hours = c.HourlyMinimumHours
+		}
+		fare = int64(hours) * c.HourlyRateCents
+		add("hourly", fmt.Sprintf("%d h", hours), fare)
+	default:
+		return nil, fmt.Errorf("unknown ride type %q", in.RideType)
+	}
+
+	if m := c.ClassMultipliers[in.VehicleClass]; m > 0 && m != 1 {
+		extra := int64(math.Round(float64(fare) * (m - 1)))
+		add("class", fmt.Sprintf("%s class (x%.2f)", in.VehicleClass, m), extra)
+		fare += extra
+	}
+
+	// Night and holiday rates don't stack; the higher one applies to the fare, not to surcharges.
+	if m, label := c.multiplier(in.ScheduledAt); m > 1 {
+		add("multiplier", fmt.Sprintf("%s (x%.2f)", label, m), int64(math.Round(float64(fare)*(m-1))))
+	}
+
+	if c.isAirport(in.Pickup) {
+		add("airport_pickup", "Airport pickup", c.AirportSurchargeCents)
+	}
+	if c.isAirport(in.Dropoff) {
+		add("airport_dropoff", "Airport drop-off", c.AirportSurchargeCents)
+	}
+
+	for _, it := range q.Items {
+		q.TotalCents += it.AmountCents
+	}
+	return q, nil
+}
+
+func (c *RateCard) multiplier(at time.Time) (float64, string) {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := at.In(loc)
+
+	best, label := 1.0, ""
+	if c.HolidayMultiplier > best {
+		day, date := local.Format("01-02"), local.Format("2006-01-02")
+		for _, h := range c.Holidays {
+			if h == day || h == date {
+				best, label = c.HolidayMultiplier, "Holiday rate"
+				break
+			}
+		}
+	}
+	if c.NightMultiplier > best && c.isNight(local.Hour()) {
+		best, label = c.NightMultiplier, "Night rate"
+	}
+	return best, label
+}
+
+func (c *RateCard) isNight(hour int) bool {
+	if c.NightStartHour == c.NightEndHour {
+		return false
+	}
+	if c.NightStartHour < c.NightEndHour {
+		return hour >= c.NightStartHour && hour < c.NightEndHour
+	}
+	return hour >= c.NightStartHour || hour < c.NightEndHour
+}
+
+func (c *RateCard) isAirport(addr string) bool {
+	addr = strings.ToLower(addr)
+	for _, k := range c.AirportKeywords {
+		if k != "" && strings.Contains(addr, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package pricing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/pricing"
+)
+
+func TestRateCard_Quote(t *testing.T) {
+	card := pricing.DefaultRateCard()
+	noon := time.Date(2030, 3, 12, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2030, 3, 12, 23, 30, 0, 0, time.UTC)
+	newYear := time.Date(2030, 1, 1, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		in    domain.QuoteReq
+		total int64
+	}{
+		{"per ride", domain.QuoteReq{RideType: domain.RidePerRide, ScheduledAt: noon, DistanceMiles: 20, DurationMinutes: 40}, 2500 + 7000 + 3000},
+		{"minimum fare", domain.QuoteReq{RideType: domain.RidePerRide, ScheduledAt: noon, DistanceMiles: 1, DurationMinutes: 5}, 7500},
+		{"airport both ends", domain.QuoteReq{RideType: domain.RidePerRide, ScheduledAt: noon, DistanceMiles: 20, DurationMinutes: 40, Pickup: "SFO Airport", Dropoff: "OAK airport"}, 12500 + 3000},
+		{"hourly below minimum", domain.QuoteReq{RideType: domain.RideHourly, ScheduledAt: noon, DurationMinutes: 30}, 2 * 9500},
+		{"hourly rounds up", domain.QuoteReq{RideType: domain.RideHourly, ScheduledAt: noon, DurationMinutes: 150}, 3 * 9500},
+		{"night", domain.QuoteReq{RideType: domain.RideHourly, ScheduledAt: night, DurationMinutes: 120}, 19000 + 3800},
+		{"holiday beats night", domain.QuoteReq{RideType: domain.RideHourly, ScheduledAt: newYear, DurationMinutes: 120}, 19000 + 9500},
+		{"dearer class", domain.QuoteReq{RideType: domain.RideHourly, ScheduledAt: noon, DurationMinutes: 120, VehicleClass: "limo"}, 19000 + 9500},
+		{"class before night", domain.QuoteReq{RideType: domain.RideHourly, ScheduledAt: night, DurationMinutes: 120, VehicleClass: "limo"}, 28500 + 5700},
+		{"unlisted class", domain.QuoteReq{RideType: domain.RideHourly, ScheduledAt: noon, DurationMinutes: 120, VehicleClass: "suv"}, 19000},
+	}
+	card.ClassMultipliers = map[string]float64{"limo": 1.5}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := card.Quote(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.TotalCents != tt.total {
+				t.Fatalf("total = %d, want %d (items %+v)", q.TotalCents, tt.total, q.Items)
+			}
+		})
+	}
+}
+
+func TestRateCard_MinTripMinutes(t *testing.T) {
+	card := pricing.DefaultRateCard()
+	if got := card.MinTripMinutes(60); got != 60 {
+		t.Fatalf("MinTripMinutes(60) = %d, want 60", got)
+	}
+	if got := card.MinTripMinutes(0.5); got != 1 {
+		t.Fatalf("MinTripMinutes(0.5) = %d, want 1", got)
+	}
+}
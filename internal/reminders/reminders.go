@@ -0,0 +1,88 @@
+// Package reminders reminds riders of their confirmed and assigned trips ahead
+// of pickup, by email or text as they chose when booking.
+package reminders
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/notify"
+	"github.com/diagnosis/luxsuv-bookings/internal/repo/postgres"
+)
+
+// Reminder is sent Lead before a trip's scheduled_at. Kind names it in booking_reminders.
+type Reminder struct {
+	Kind string
+	Lead time.Duration
+}
+
+// DefaultSchedule reminds riders a day and an hour before pickup.
+var DefaultSchedule = []Reminder{
+	{Kind: "24h", Lead: 24 * time.Hour},
+	{Kind: "1h", Lead: time.Hour},
+}
+
+type Scheduler struct {
+	Repo     postgres.ReminderRepo
+	Notifier *notify.Notifier
+	// Schedule must be sorted by decreasing Lead. A trip booked too late for
+	// a reminder only gets the later ones.
+	Schedule []Reminder
+	// Interval is how often due reminders are looked for.
+	Interval time.Duration
+	// Batch caps the reminders of each kind sent per run.
+	Batch int
+	Now   func() time.Time
+}
+
+func New(repo postgres.ReminderRepo, n *notify.Notifier) *Scheduler {
+	return &Scheduler{
+		Repo:     repo,
+		Notifier: n,
+		Schedule: DefaultSchedule,
+		Interval: time.Minute,
+		Batch:    100,
+		Now:      time.Now,
+	}
+}
+
+// Run sends due reminders until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	t := time.NewTicker(s.Interval)
+	defer t.Stop()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			log.Printf("reminders: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce claims every due reminder, returning how many bookings were
+// reminded. Each reminder is queued in the outbox with its claim, which
+// delivers it.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	now := s.Now()
+	sent := 0
+	var errs []error
+	for i, r := range s.Schedule {
+		// Trips within the next reminder's lead are left to that reminder.
+		from := now
+		if i+1 < len(s.Schedule) {
+			from = now.Add(s.Schedule[i+1].Lead)
+		}
+		bookings, err := s.Repo.ClaimReminders(ctx, r.Kind, from, now.Add(r.Lead), s.Batch, s.Notifier.TripReminders)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent += len(bookings)
+	}
+	return sent, errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package reminders_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/notify"
+	"github.com/diagnosis/luxsuv-bookings/internal/reminders"
+)
+
+type claim struct {
+	kind     string
+	from, to time.Time
+}
+
+// fakeRepo hands out each booking once per kind, like booking_reminders does.
+type fakeRepo struct {
+	bookings []domain.Booking
+	sent     map[string]bool
+	claims   []claim
+	queued   []domain.OutboxMessage
+}
+
+func (f *fakeRepo) ClaimReminders(ctx context.Context, kind string, from, to time.Time, limit int,
+	messages func(domain.Booking) ([]domain.OutboxMessage, error)) ([]domain.Booking, error) {
+	f.claims = append(f.claims, claim{kind, from, to})
+	var out []domain.Booking
+	for _, b := range f.bookings {
+		key := kind + "/" + b.RiderEmail
+		if f.sent[key] || !b.ScheduledAt.After(from) || b.ScheduledAt.After(to) {
+			continue
+		}
+		f.sent[key] = true
+		msgs, err := messages(b)
+		if err != nil {
+			return nil, err
+		}
+		f.queued = append(f.queued, msgs...)
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) Purge(ctx context.Context, olderThan time.Duration) (int64, error) { return 0, nil }
+
+func TestRunOnce(t *testing.T) {
+	now := time.Date(2030, 3, 12, 8, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{
+		sent: map[string]bool{},
+		bookings: []domain.Booking{
+			{ID: 1, RiderEmail: "tomorrow@example.com", ScheduledAt: now.Add(20 * time.Hour), ReminderChannel: domain.ChannelEmail},
+			{ID: 2, RiderEmail: "soon@example.com", RiderPhone: "+14155550100", ScheduledAt: now.Add(30 * time.Minute), ReminderChannel: domain.ChannelSMS},
+			{ID: 3, RiderEmail: "later@example.com", ScheduledAt: now.Add(48 * time.Hour), ReminderChannel: domain.ChannelEmail},
+		},
+	}
+	s := reminders.New(repo, notify.New("https://app.example.com"))
+	s.Now = func() time.Time { return now }
+
+	n, err := s.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("reminded %d bookings, want 2", n)
+	}
+	want := []claim{
+		{"24h", now.Add(time.Hour), now.Add(24 * time.Hour)},
+		{"1h", now, now.Add(time.Hour)},
+	}
+	for i, c := range repo.claims {
+		if c != want[i] {
+			t.Errorf("claim %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+	// Texts are queued with their claim like emails, so a failed send is retried.
+	q := repo.queued
+	if len(q) != 2 || q[0].Channel != domain.ChannelEmail || q[0].ToEmail != "tomorrow@example.com" ||
+		q[1].Channel != domain.ChannelSMS || q[1].ToPhone != "+14155550100" {
+		t.Errorf("queued = %+v", q)
+	}
+
+	// A second run, e.g. on another replica, finds nothing left to send.
+	if n, err := s.RunOnce(context.Background()); err != nil || n != 0 {
+		t.Errorf("second run reminded %d bookings (err %v), want 0", n, err)
+	}
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/audit"
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// BookingMailer renders the emails a booking event sends. driver is set for
+// domain.EventAssigned events.
+type BookingMailer interface {
+	BookingEmails(e domain.BookingEvent, b domain.Booking, driver *domain.Recipient) ([]domain.OutboxMessage, error)
+}
+
+// recordEvent appends e to the booking's history inside tx, stamped with the
+// actor and request ID carried by ctx. Guests acting through a manage token
+// have no email in ctx, so the booking's rider email is recorded for them.
+// The emails the event sends are queued in the outbox in the same tx, so they
+// go out if and only if the change commits.
+func (r *BookingRepoImpl) recordEvent(ctx context.Context, tx pgx.Tx, e domain.BookingEvent) error {
+	const q = `
+		INSERT INTO booking_events (
+			booking_id, type, from_status, to_status, changes,
+			actor_role, actor_user_id, actor_email, request_id
+		)
+		SELECT $1, $2, $3, $4, $5::jsonb, $6, $7,
+		       CASE WHEN $8 = '' AND $6 = 'guest' THEN b.rider_email ELSE $8 END,
+		       $9
+		FROM bookings b WHERE b.id = $1`
+
+	a := audit.ActorFrom(ctx)
+	var changes map[string]domain.FieldChange
+	if len(e.Changes) > 0 {
+		changes = e.Changes
+	}
+	_, err := tx.Exec(ctx, q,
+		e.BookingID, e.Type, e.FromStatus, e.ToStatus, changes,
+		a.Role, a.UserID, a.Email, audit.RequestID(ctx),
+	)
+	if err != nil || r.Mailer == nil {
+		return err
+	}
+	return r.queueEmails(ctx, tx, e)
+}
+
+func (r *BookingRepoImpl) queueEmails(ctx context.Context, tx pgx.Tx, e domain.BookingEvent) error {
+	var b domain.Booking
+	if err := tx.QueryRow(ctx, `SELECT `+bookingCols+` FROM bookings WHERE id=$1`, e.BookingID).Scan(bookingDest(&b)...); err != nil {
+		return err
+	}
+	var driver *domain.Recipient
+	if e.Type == domain.EventAssigned && b.DriverID != nil {
+		driver = &domain.Recipient{}
+		if err := tx.QueryRow(ctx, `SELECT email, name, locale FROM users WHERE id=$1`, *b.DriverID).Scan(&driver.Email, &driver.Name, &driver.Locale); err != nil {
+			return err
+		}
+	}
+
+	msgs, err := r.Mailer.BookingEmails(e, b, driver)
+	if err != nil {
+		return fmt.Errorf("render %s emails for booking %d: %w", e.Type, b.ID, err)
+	}
+	for _, m := range msgs {
+		if err := enqueueMessage(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListEvents returns the booking's history, oldest first.
+func (r *BookingRepoImpl) ListEvents(ctx context.Context, bookingID int64) ([]domain.BookingEvent, error) {
+	const q = `
+		SELECT id, booking_id, type, from_status, to_status, changes,
+		       actor_role, actor_user_id, actor_email, request_id, created_at
+		FROM booking_events
+		WHERE booking_id=$1
+		ORDER BY id`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, q, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.BookingEvent{}
+	for rows.Next() {
+		var e domain.BookingEvent
+		if err := rows.Scan(
+			&e.ID, &e.BookingID, &e.Type, &e.FromStatus, &e.ToStatus, &e.Changes,
+			&e.ActorRole, &e.ActorUserID, &e.ActorEmail, &e.RequestID, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -2,11 +2,15 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/diagnosis/luxsuv-bookings/internal/domain"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,53 +18,152 @@ type BookingRepo interface {
 	CreateGuest(ctx context.Context, in *domain.BookingGuestReq) (*domain.Booking, error)
 	GetByIDWithToken(ctx context.Context, id int64, token string) (*domain.Booking, error)
 	CancelWithToken(ctx context.Context, id int64, token string) (bool, error)
+	UpdateStatus(ctx context.Context, id int64, next domain.BookingStatus) (bool, error)
+	AssignDriver(ctx context.Context, id, driverID int64) (bool, error)
+	UnassignDriver(ctx context.Context, id int64) (bool, error)
+	StartTrip(ctx context.Context, id, driverID int64) (bool, error)
+	CompleteTrip(ctx context.Context, id, driverID int64) (bool, error)
+	Search(ctx context.Context, f domain.BookingFilter, limit, offset int) ([]domain.Booking, error)
 	List(ctx context.Context, limit, offset int) ([]domain.Booking, error)
 	ListByStatus(ctx context.Context, status domain.BookingStatus, limit, offset int) ([]domain.Booking, error)
 	GetByID(ctx context.Context, id int64) (*domain.Booking, error)
 	ListByUserID(ctx context.Context, userID int64, limit, offset int, status *domain.BookingStatus) ([]domain.Booking, error)
 	CreateForUser(ctx context.Context, userID int64, in *domain.BookingGuestReq) (*domain.Booking, error)
+	// UpdateGuest applies patch to the booking with manage token token. Like
+	// UpdateForUser, it fails with domain.ErrBookingLocked once the booking has
+	// left domain.EditableStatuses, and with domain.ErrSlotFull if the patch
+	// moves the trip to a time or class with no free vehicle.
 	UpdateGuest(ctx context.Context, id int64, token string, patch domain.GuestPatch) (*domain.Booking, error)
+	// UpdateForUser applies patch to a booking owned by userID while it is still in one of
+	// domain.EditableStatuses. It returns nil if the user has no such booking and
+	// domain.ErrBookingLocked if the booking exists but can no longer be edited.
+	UpdateForUser(ctx context.Context, id, userID int64, patch domain.GuestPatch) (*domain.Booking, error)
 	ListByEmail(ctx context.Context, email string, limit, offset int, status *domain.BookingStatus) ([]domain.Booking, error)
+	// ListBusy returns the intervals in which bookings in domain.OccupyingStatuses
+	// hold a vehicle, for every booking overlapping [from, to).
+	ListBusy(ctx context.Context, from, to time.Time) ([]domain.BusyInterval, error)
+	// ListEvents returns the booking's history, oldest first.
+	ListEvents(ctx context.Context, bookingID int64) ([]domain.BookingEvent, error)
 }
 
-type BookingRepoImpl struct{ pool *pgxpool.Pool }
+type BookingRepoImpl struct {
+	pool *pgxpool.Pool
+	// Mailer, when set, queues the emails each booking event sends.
+	Mailer BookingMailer
+}
 
 func NewBookingRepo(pool *pgxpool.Pool) *BookingRepoImpl { return &BookingRepoImpl{pool: pool} }
 
 const bookingCols = `id, manage_token, status,
 rider_name, rider_email, rider_phone,
 pickup, dropoff, scheduled_at, notes,
-passengers, luggages, ride_type,
-user_id, driver_id, created_at, updated_at`
+passengers, luggages, ride_type, duration_minutes, vehicle_class,
+pickup_location, dropoff_location,
+user_id, driver_id, started_at, completed_at,
+quote_id::text, fare_cents, locale, reminder_channel,
+created_at, updated_at`
+
+// bookingDest returns the scan destinations for bookingCols, in order.
+func bookingDest(b *domain.Booking) []any {
+	return []any{
+		&b.ID, &b.ManageToken, &b.Status,
+		&b.RiderName, &b.RiderEmail, &b.RiderPhone,
+		&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
+		&b.Passengers, &b.Luggages, &b.RideType, &b.DurationMinutes, &b.VehicleClass,
+		&b.PickupLocation, &b.DropoffLocation,
+		&b.UserID, &b.DriverID, &b.StartedAt, &b.CompletedAt,
+		&b.QuoteID, &b.FareCents, &b.Locale, &b.ReminderChannel,
+		&b.CreatedAt, &b.UpdatedAt,
+	}
+}
 
 func (r *BookingRepoImpl) CreateGuest(ctx context.Context, in *domain.BookingGuestReq) (*domain.Booking, error) {
+	return r.create(ctx, nil, in)
+}
+
+// create inserts a pending booking. The active vehicles of the booking's class
+// are locked first and the insert fails with domain.ErrSlotFull if they are all
+// taken at some point of the trip, so concurrent creates cannot both take the
+// last vehicle. When in.QuoteID is set, the quote is claimed for the booking in
+// the same transaction and its total becomes the fare; a quote that is unknown,
+// expired, already claimed or priced for a different trip fails the whole insert
+// with domain.ErrQuoteInvalid.
+func (r *BookingRepoImpl) create(ctx context.Context, userID *int64, in *domain.BookingGuestReq) (*domain.Booking, error) {
 	const q = `INSERT INTO bookings (
     manage_token, status,
     rider_name, rider_email, rider_phone,
     pickup, dropoff, scheduled_at, notes,
-    passengers, luggages, ride_type
-  ) VALUES ($1,'pending',$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
-  RETURNING ` + bookingCols
+    passengers, luggages, ride_type, duration_minutes, vehicle_class,
+    pickup_location, dropoff_location,
+    user_id, locale, reminder_channel
+  ) VALUES ($1,'pending',$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,
+    COALESCE(NULLIF($17,''),'en'), COALESCE(NULLIF($18,''),'email'))
+  RETURNING id`
+
+	const claimQuote = `
+		WITH q AS (
+			UPDATE quotes SET booking_id=$1
+			WHERE id=$2 AND booking_id IS NULL AND expires_at > now()
+			  AND ride_type=$3 AND pickup=$4 AND dropoff=$5 AND scheduled_at=$6
+			  AND (ride_type <> 'hourly' OR duration_minutes = $7)
+			  AND vehicle_class=$8
+			RETURNING id, total_cents
+		)
+		UPDATE bookings b SET quote_id=q.id, fare_cents=q.total_cents
+		FROM q WHERE b.id=$1`
+
+	if in.QuoteID != "" {
+		if _, err := uuid.Parse(in.QuoteID); err != nil {
+			return nil, domain.ErrQuoteInvalid
+		}
+	}
 
 	tok := uuid.NewString()
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var b domain.Booking
-	err := r.pool.QueryRow(ctx, q, tok,
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	trip := domain.Booking{ScheduledAt: in.ScheduledAt, DurationMinutes: in.DurationMinutes}
+	if err := reserveVehicle(ctx, tx, in.VehicleClass, trip.ScheduledAt, trip.EndsAt(), 0); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	if err := tx.QueryRow(ctx, q, tok,
 		in.RiderName, in.RiderEmail, in.RiderPhone,
 		in.Pickup, in.Dropoff, in.ScheduledAt, in.Notes,
-		in.Passengers, in.Luggages, in.RideType,
-	).Scan(
-		&b.ID, &b.ManageToken, &b.Status,
-		&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-		&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-		&b.Passengers, &b.Luggages, &b.RideType,
-		&b.UserID, // ← add (nullable: *int64 on the struct)
-		&b.DriverID,
-		&b.CreatedAt, &b.UpdatedAt,
-	)
-	if err != nil {
+		in.Passengers, in.Luggages, in.RideType, in.DurationMinutes, in.VehicleClass,
+		in.PickupLocation, in.DropoffLocation,
+		userID, in.Locale, in.ReminderChannel,
+	).Scan(&id); err != nil {
+		return nil, mapBookingErr(err)
+	}
+
+	if in.QuoteID != "" {
+		tag, err := tx.Exec(ctx, claimQuote, id, in.QuoteID, in.RideType, in.Pickup, in.Dropoff, in.ScheduledAt, in.DurationMinutes, in.VehicleClass)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, domain.ErrQuoteInvalid
+		}
+	}
+
+	pending := domain.BookingPending
+	if err := r.recordEvent(ctx, tx, domain.BookingEvent{BookingID: id, Type: domain.EventCreated, ToStatus: &pending}); err != nil {
+		return nil, err
+	}
+
+	var b domain.Booking
+	if err := tx.QueryRow(ctx, `SELECT `+bookingCols+` FROM bookings WHERE id=$1`, id).Scan(bookingDest(&b)...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 	return &b, nil
@@ -72,15 +175,7 @@ func (r *BookingRepoImpl) GetByIDWithToken(ctx context.Context, id int64, token
 	defer cancel()
 
 	var b domain.Booking
-	err := r.pool.QueryRow(ctx, q, id, token).Scan(
-		&b.ID, &b.ManageToken, &b.Status,
-		&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-		&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-		&b.Passengers, &b.Luggages, &b.RideType,
-		&b.UserID, // ← add (nullable: *int64 on the struct)
-		&b.DriverID,
-		&b.CreatedAt, &b.UpdatedAt,
-	)
+	err := r.pool.QueryRow(ctx, q, id, token).Scan(bookingDest(&b)...)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -88,14 +183,151 @@ func (r *BookingRepoImpl) GetByIDWithToken(ctx context.Context, id int64, token
 }
 
 func (r *BookingRepoImpl) CancelWithToken(ctx context.Context, id int64, token string) (bool, error) {
-	const q = `UPDATE bookings SET status='canceled' WHERE id=$1 AND manage_token=$2 AND status <> 'canceled'`
+	return r.transition(ctx, id, domain.BookingCanceled, domain.StatusesAllowing(domain.BookingCanceled), ``, `AND manage_token=$4`, token)
+}
+
+func (r *BookingRepoImpl) UpdateStatus(ctx context.Context, id int64, next domain.BookingStatus) (bool, error) {
+	return r.transition(ctx, id, next, domain.StatusesAllowing(next), ``, ``)
+}
+
+// AssignDriver sets the booking's driver and moves it to assigned. An already
+// assigned booking may be reassigned to another driver. The driver's user row
+// is locked for the duration, so two concurrent assignments cannot both pass
+// the overlap check.
+func (r *BookingRepoImpl) AssignDriver(ctx context.Context, id, driverID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var role string
+	err = tx.QueryRow(ctx, `SELECT role FROM users WHERE id=$1 FOR UPDATE`, driverID).Scan(&role)
+	if err == pgx.ErrNoRows || (err == nil && role != "driver") {
+		return false, domain.ErrNotDriver
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var b domain.Booking
+	err = tx.QueryRow(ctx, `SELECT status, scheduled_at, duration_minutes, driver_id FROM bookings WHERE id=$1 FOR UPDATE`, id).
+		Scan(&b.Status, &b.ScheduledAt, &b.DurationMinutes, &b.DriverID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if b.Status != domain.BookingAssigned && !b.Status.CanTransitionTo(domain.BookingAssigned) {
+		return false, &domain.TransitionError{From: b.Status, To: domain.BookingAssigned}
+	}
+
+	const overlapQ = `
+		SELECT EXISTS (
+			SELECT 1 FROM bookings
+			WHERE driver_id=$1 AND id<>$2
+			  AND status IN ('assigned','on_trip')
+			  AND scheduled_at < $4::timestamptz
+			  AND scheduled_at + COALESCE(make_interval(mins => duration_minutes), $5::interval) > $3::timestamptz
+		)`
+	var busy bool
+	if err := tx.QueryRow(ctx, overlapQ, driverID, id, b.ScheduledAt, b.EndsAt(), domain.DriverRideWindow).Scan(&busy); err != nil {
+		return false, err
+	}
+	if busy {
+		return false, domain.ErrDriverUnavailable
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE bookings SET status='assigned', driver_id=$2 WHERE id=$1`, id, driverID); err != nil {
+		return false, err
+	}
+	assigned := domain.BookingAssigned
+	if err := r.recordEvent(ctx, tx, domain.BookingEvent{
+		BookingID: id, Type: domain.EventAssigned, FromStatus: &b.Status, ToStatus: &assigned,
+		Changes: map[string]domain.FieldChange{"driver_id": {From: b.DriverID, To: driverID}},
+	}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// UnassignDriver clears the driver of an assigned booking and returns it to confirmed.
+func (r *BookingRepoImpl) UnassignDriver(ctx context.Context, id int64) (bool, error) {
+	return r.transition(ctx, id, domain.BookingConfirmed, []domain.BookingStatus{domain.BookingAssigned}, `, driver_id=NULL`, ``)
+}
+
+// StartTrip moves an assigned booking of the given driver to on_trip and records started_at.
+func (r *BookingRepoImpl) StartTrip(ctx context.Context, id, driverID int64) (bool, error) {
+	return r.transition(ctx, id, domain.BookingOnTrip, domain.StatusesAllowing(domain.BookingOnTrip), `, started_at=now()`, `AND driver_id=$4`, driverID)
+}
+
+// CompleteTrip moves an on_trip booking of the given driver to completed and records completed_at.
+func (r *BookingRepoImpl) CompleteTrip(ctx context.Context, id, driverID int64) (bool, error) {
+	return r.transition(ctx, id, domain.BookingCompleted, domain.StatusesAllowing(domain.BookingCompleted), `, completed_at=now()`, `AND driver_id=$4`, driverID)
+}
+
+// transition moves the booking matching id (and the extra match clause) from
+// one of the given statuses to next in a single statement, applying the extra
+// set clause as well. Placeholders in set and match start at $4. The row is
+// locked before its current status is checked, so concurrent transitions
+// serialize. The change is recorded in the booking's history in the same
+// transaction. It returns false when no booking matches and a
+// *domain.TransitionError when the current status does not allow the move.
+func (r *BookingRepoImpl) transition(ctx context.Context, id int64, next domain.BookingStatus, from []domain.BookingStatus, set, match string, args ...any) (bool, error) {
+	q := `
+		WITH cur AS (
+			SELECT id, status, driver_id FROM bookings WHERE id=$1 ` + match + ` FOR UPDATE
+		), upd AS (
+			UPDATE bookings b SET status=$2` + set + `
+			FROM cur
+			WHERE b.id = cur.id AND cur.status::text = ANY($3::text[])
+			RETURNING b.id
+		)
+		SELECT cur.status, cur.driver_id, EXISTS (SELECT 1 FROM upd) FROM cur`
+
+	allowed := make([]string, 0, len(from))
+	for _, st := range from {
+		allowed = append(allowed, string(st))
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	ct, err := r.pool.Exec(ctx, q, id, token)
+
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return false, err
 	}
-	return ct.RowsAffected() > 0, nil
+	defer tx.Rollback(ctx)
+
+	var (
+		cur      domain.BookingStatus
+		driverID *int64
+		updated  bool
+	)
+	err = tx.QueryRow(ctx, q, append([]any{id, next, allowed}, args...)...).Scan(&cur, &driverID, &updated)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !updated {
+		return false, &domain.TransitionError{From: cur, To: next}
+	}
+
+	ev := domain.BookingEvent{BookingID: id, Type: domain.EventStatusChanged, FromStatus: &cur, ToStatus: &next}
+	if cur == domain.BookingAssigned && next == domain.BookingConfirmed {
+		ev.Type = domain.EventUnassigned
+		ev.Changes = map[string]domain.FieldChange{"driver_id": {From: driverID, To: nil}}
+	}
+	if err := r.recordEvent(ctx, tx, ev); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
 }
 
 func (r *BookingRepoImpl) List(ctx context.Context, limit, offset int) ([]domain.Booking, error) {
@@ -117,15 +349,7 @@ func (r *BookingRepoImpl) List(ctx context.Context, limit, offset int) ([]domain
 	bs := make([]domain.Booking, 0, limit)
 	for rows.Next() {
 		var b domain.Booking
-		if err := rows.Scan(
-			&b.ID, &b.ManageToken, &b.Status,
-			&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-			&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-			&b.Passengers, &b.Luggages, &b.RideType,
-			&b.UserID, // ← add (nullable: *int64 on the struct)
-			&b.DriverID,
-			&b.CreatedAt, &b.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(bookingDest(&b)...); err != nil {
 			return nil, err
 		}
 		bs = append(bs, b)
@@ -160,35 +384,73 @@ func (r *BookingRepoImpl) ListByStatus(ctx context.Context, status domain.Bookin
 	bs := make([]domain.Booking, 0, limit)
 	for rows.Next() {
 		var b domain.Booking
-		if err := rows.Scan(
-			&b.ID, &b.ManageToken, &b.Status,
-			&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-			&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-			&b.Passengers, &b.Luggages, &b.RideType,
-			&b.UserID, // ← add (nullable: *int64 on the struct)
-			&b.DriverID,
-			&b.CreatedAt, &b.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(bookingDest(&b)...); err != nil {
 			return nil, err
 		}
 		bs = append(bs, b)
 	}
 	return bs, rows.Err()
 }
+func (r *BookingRepoImpl) Search(ctx context.Context, f domain.BookingFilter, limit, offset int) ([]domain.Booking, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	q := `SELECT ` + bookingCols + ` FROM bookings WHERE true`
+	args := []any{}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		q += fmt.Sprintf(" AND "+cond, len(args))
+	}
+	if f.Status != nil {
+		add(`status=$%d`, *f.Status)
+	}
+	if f.RiderEmail != "" {
+		add(`lower(rider_email)=lower($%d)`, f.RiderEmail)
+	}
+	if f.UserID != nil {
+		add(`user_id=$%d`, *f.UserID)
+	}
+	if f.DriverID != nil {
+		add(`driver_id=$%d`, *f.DriverID)
+	}
+	if f.From != nil {
+		add(`scheduled_at >= $%d`, *f.From)
+	}
+	if f.To != nil {
+		add(`scheduled_at < $%d`, *f.To)
+	}
+	args = append(args, limit, offset)
+	q += fmt.Sprintf(` ORDER BY scheduled_at DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Booking, 0, limit)
+	for rows.Next() {
+		var b domain.Booking
+		if err := rows.Scan(bookingDest(&b)...); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
 func (r *BookingRepoImpl) GetByID(ctx context.Context, id int64) (*domain.Booking, error) {
 	const q = `SELECT ` + bookingCols + ` FROM bookings WHERE id=$1`
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	var b domain.Booking
-	if err := r.pool.QueryRow(ctx, q, id).Scan(
-		&b.ID, &b.ManageToken, &b.Status,
-		&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-		&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-		&b.Passengers, &b.Luggages, &b.RideType,
-		&b.UserID, // ← add (nullable: *int64 on the struct)
-		&b.DriverID,
-		&b.CreatedAt, &b.UpdatedAt,
-	); err != nil {
+	if err := r.pool.QueryRow(ctx, q, id).Scan(bookingDest(&b)...); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
@@ -227,15 +489,7 @@ func (r *BookingRepoImpl) ListByUserID(ctx context.Context, userID int64, limit,
 	out := make([]domain.Booking, 0, limit)
 	for rows.Next() {
 		var b domain.Booking
-		if err := rows.Scan(
-			&b.ID, &b.ManageToken, &b.Status,
-			&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-			&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-			&b.Passengers, &b.Luggages, &b.RideType,
-			&b.UserID, // ← add (nullable: *int64 on the struct)
-			&b.DriverID,
-			&b.CreatedAt, &b.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(bookingDest(&b)...); err != nil {
 			return nil, err
 		}
 		out = append(out, b)
@@ -243,37 +497,9 @@ func (r *BookingRepoImpl) ListByUserID(ctx context.Context, userID int64, limit,
 	return out, rows.Err()
 }
 func (r *BookingRepoImpl) CreateForUser(ctx context.Context, userID int64, in *domain.BookingGuestReq) (*domain.Booking, error) {
-	const q = `INSERT INTO bookings (
-        manage_token, status,
-        rider_name, rider_email, rider_phone,
-        pickup, dropoff, scheduled_at, notes,
-        passengers, luggages, ride_type,
-        user_id
-    ) VALUES ($1,'pending',$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
-    RETURNING ` + bookingCols
-
-	tok := uuid.NewString()
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	var b domain.Booking
-	err := r.pool.QueryRow(ctx, q, tok,
-		in.RiderName, in.RiderEmail, in.RiderPhone,
-		in.Pickup, in.Dropoff, in.ScheduledAt, in.Notes,
-		in.Passengers, in.Luggages, in.RideType,
-		userID,
-	).Scan(
-		&b.ID, &b.ManageToken, &b.Status,
-		&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-		&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-		&b.Passengers, &b.Luggages, &b.RideType,
-		&b.UserID, &b.DriverID, &b.CreatedAt, &b.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return &b, nil
+	return r.create(ctx, &userID, in)
 }
+
 func (r *BookingRepoImpl) ListByEmail(ctx context.Context, email string, limit, offset int, status *domain.BookingStatus) ([]domain.Booking, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20
@@ -303,15 +529,7 @@ func (r *BookingRepoImpl) ListByEmail(ctx context.Context, email string, limit,
 	var out []domain.Booking
 	for rows.Next() {
 		var b domain.Booking
-		if err := rows.Scan(
-			&b.ID, &b.ManageToken, &b.Status,
-			&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-			&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-			&b.Passengers, &b.Luggages, &b.RideType,
-			&b.UserID, // ← add (nullable: *int64 on the struct)
-			&b.DriverID,
-			&b.CreatedAt, &b.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(bookingDest(&b)...); err != nil {
 			return nil, err
 		}
 		out = append(out, b)
@@ -332,36 +550,227 @@ func (r *BookingRepoImpl) UpdateGuest(ctx context.Context, id int64, token strin
             passengers   = COALESCE($9, passengers),
             luggages     = COALESCE($10, luggages),
             ride_type    = COALESCE($11, ride_type),
+            duration_minutes = CASE WHEN $11 = 'per_ride' THEN NULL ELSE COALESCE($12, duration_minutes) END,
+            vehicle_class    = COALESCE($13, vehicle_class),
+            pickup_location  = CASE WHEN $5::text IS NULL THEN pickup_location ELSE $14::jsonb END,
+            dropoff_location = CASE WHEN $6::text IS NULL THEN dropoff_location ELSE $15::jsonb END,
             updated_at   = now()
         WHERE id=$1 AND manage_token=$2
         RETURNING ` + bookingCols
 
+	const lockQ = `SELECT ` + bookingCols + ` FROM bookings WHERE id=$1 AND manage_token=$2 FOR UPDATE`
+	return r.patch(ctx, p, lockQ, []any{id, token}, checkEditable, q,
+		id, token,
+		p.RiderName,       // $3  *string
+		p.RiderPhone,      // $4  *string
+		p.Pickup,          // $5  *string
+		p.Dropoff,         // $6  *string
+		p.ScheduledAt,     // $7  *time.Time
+		p.Notes,           // $8  *string
+		p.Passengers,      // $9  *int
+		p.Luggages,        // $10 *int
+		p.RideType,        // $11 *domain.RideType
+		p.DurationMinutes, // $12 *int
+		p.VehicleClass,    // $13 *string
+		p.PickupLocation,  // $14 *domain.Location
+		p.DropoffLocation, // $15 *domain.Location
+	)
+}
+
+func (r *BookingRepoImpl) UpdateForUser(ctx context.Context, id, userID int64, p domain.GuestPatch) (*domain.Booking, error) {
+	const q = `
+        UPDATE bookings
+        SET
+            rider_name   = COALESCE($4, rider_name),
+            rider_phone  = COALESCE($5, rider_phone),
+            pickup       = COALESCE($6, pickup),
+            dropoff      = COALESCE($7, dropoff),
+            scheduled_at = COALESCE($8, scheduled_at),
+            notes        = COALESCE($9, notes),
+            passengers   = COALESCE($10, passengers),
+            luggages     = COALESCE($11, luggages),
+            ride_type    = COALESCE($12, ride_type),
+            duration_minutes = CASE WHEN $12 = 'per_ride' THEN NULL ELSE COALESCE($13, duration_minutes) END,
+            vehicle_class    = COALESCE($14, vehicle_class),
+            pickup_location  = CASE WHEN $6::text IS NULL THEN pickup_location ELSE $15::jsonb END,
+            dropoff_location = CASE WHEN $7::text IS NULL THEN dropoff_location ELSE $16::jsonb END,
+            updated_at   = now()
+        WHERE id=$1 AND user_id=$2 AND status::text = ANY($3::text[])
+        RETURNING ` + bookingCols
+
+	const lockQ = `SELECT ` + bookingCols + ` FROM bookings WHERE id=$1 AND user_id=$2 FOR UPDATE`
+
+	editable := make([]string, len(domain.EditableStatuses))
+	for i, s := range domain.EditableStatuses {
+		editable[i] = string(s)
+	}
+
+	return r.patch(ctx, p, lockQ, []any{id, userID}, checkEditable, q,
+		id, userID, editable,
+		p.RiderName,       // $4  *string
+		p.RiderPhone,      // $5  *string
+		p.Pickup,          // $6  *string
+		p.Dropoff,         // $7  *string
+		p.ScheduledAt,     // $8  *time.Time
+		p.Notes,           // $9  *string
+		p.Passengers,      // $10 *int
+		p.Luggages,        // $11 *int
+		p.RideType,        // $12 *domain.RideType
+		p.DurationMinutes, // $13 *int
+		p.VehicleClass,    // $14 *string
+		p.PickupLocation,  // $15 *domain.Location
+		p.DropoffLocation, // $16 *domain.Location
+	)
+}
+
+// checkEditable tells "not yours" (nil from patch) apart from "too late to
+// edit": assigned and later bookings are the driver's schedule.
+func checkEditable(old domain.Booking) error {
+	if !slices.Contains(domain.EditableStatuses, old.Status) {
+		return domain.ErrBookingLocked
+	}
+	return nil
+}
+
+// patch locks the booking selected by lockQ, lets check reject it, applies the
+// update q (which must return bookingCols), re-reserves a vehicle if the trip
+// moved and records the fields p changed, all in one transaction. It returns nil when lockQ matches no booking.
+func (r *BookingRepoImpl) patch(ctx context.Context, p domain.GuestPatch, lockQ string, lockArgs []any, check func(domain.Booking) error, q string, args ...any) (*domain.Booking, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var old domain.Booking
+	err = tx.QueryRow(ctx, lockQ, lockArgs...).Scan(bookingDest(&old)...)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if check != nil {
+		if err := check(old); err != nil {
+			return nil, err
+		}
+	}
+
 	var b domain.Booking
-	err := r.pool.QueryRow(ctx, q,
-		id, token,
-		p.RiderName,   // $3  *string
-		p.RiderPhone,  // $4  *string
-		p.Pickup,      // $5  *string
-		p.Dropoff,     // $6  *string
-		p.ScheduledAt, // $7  *time.Time
-		p.Notes,       // $8  *string
-		p.Passengers,  // $9  *int
-		p.Luggages,    // $10 *int
-		p.RideType,    // $11 *domain.RideType
-	).Scan(
-		&b.ID, &b.ManageToken, &b.Status,
-		&b.RiderName, &b.RiderEmail, &b.RiderPhone,
-		&b.Pickup, &b.Dropoff, &b.ScheduledAt, &b.Notes,
-		&b.Passengers, &b.Luggages, &b.RideType,
-		&b.UserID, &b.DriverID, &b.CreatedAt, &b.UpdatedAt,
-	)
+	err = tx.QueryRow(ctx, q, args...).Scan(bookingDest(&b)...)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
-	return &b, err
+	if err != nil {
+		return nil, mapBookingErr(err)
+	}
+
+	// A booking moved to another time, length or class needs a vehicle there too.
+	moved := b.VehicleClass != old.VehicleClass || !b.ScheduledAt.Equal(old.ScheduledAt) || !b.EndsAt().Equal(old.EndsAt())
+	if moved && slices.Contains(domain.OccupyingStatuses, b.Status) {
+		if err := reserveVehicle(ctx, tx, b.VehicleClass, b.ScheduledAt, b.EndsAt(), b.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := p.Diff(old, b)
+	if domain.FareInvalidated(changes) && (b.QuoteID != nil || b.FareCents != nil) {
+		// The quote priced the old trip; the new one has no agreed fare yet.
+		if _, err := tx.Exec(ctx, `UPDATE bookings SET quote_id=NULL, fare_cents=NULL WHERE id=$1`, b.ID); err != nil {
+			return nil, err
+		}
+		changes["quote_id"] = domain.FieldChange{From: b.QuoteID, To: nil}
+		changes["fare_cents"] = domain.FieldChange{From: b.FareCents, To: nil}
+		b.QuoteID, b.FareCents = nil, nil
+	}
+	if len(changes) > 0 {
+		if err := r.recordEvent(ctx, tx, domain.BookingEvent{BookingID: b.ID, Type: domain.EventUpdated, Changes: changes}); err != nil {
+			return nil, err
+		}
+	}
+	return &b, tx.Commit(ctx)
+}
+
+// busyQ selects the busy intervals of occupying bookings overlapping [$1, $2),
+// optionally restricted to vehicle class $5 and leaving out booking $6.
+const busyQ = `
+	SELECT vehicle_class, scheduled_at, ends_at FROM (
+		SELECT vehicle_class, scheduled_at,
+		       scheduled_at + COALESCE(make_interval(mins => duration_minutes), $3::interval) AS ends_at
+		FROM bookings
+		WHERE status::text = ANY($4::text[]) AND ($5 = '' OR vehicle_class = $5) AND id <> $6
+	) b
+	WHERE scheduled_at < $2::timestamptz AND ends_at > $1::timestamptz`
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func listBusy(ctx context.Context, db querier, class string, from, to time.Time, exclude int64) ([]domain.BusyInterval, error) {
+	statuses := make([]string, 0, len(domain.OccupyingStatuses))
+	for _, st := range domain.OccupyingStatuses {
+		statuses = append(statuses, string(st))
+	}
+
+	rows, err := db.Query(ctx, busyQ, from, to, domain.DriverRideWindow, statuses, class, exclude)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.BusyInterval
+	for rows.Next() {
+		var b domain.BusyInterval
+		if err := rows.Scan(&b.Class, &b.Start, &b.End); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (r *BookingRepoImpl) ListBusy(ctx context.Context, from, to time.Time) ([]domain.BusyInterval, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return listBusy(ctx, r.pool, "", from, to, 0)
+}
+
+// reserveVehicle locks the active vehicles of class and checks that at least one
+// of them is free for all of [start, end), not counting booking exclude (the one
+// being moved, or 0). The locks are held until tx ends.
+func reserveVehicle(ctx context.Context, tx pgx.Tx, class string, start, end time.Time, exclude int64) error {
+	const lockQ = `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM vehicles WHERE class=$1 AND active ORDER BY id FOR UPDATE
+		) v`
+	var fleet int
+	if err := tx.QueryRow(ctx, lockQ, class).Scan(&fleet); err != nil {
+		return err
+	}
+	if fleet == 0 {
+		return domain.ErrUnknownVehicleClass
+	}
+
+	busy, err := listBusy(ctx, tx, class, start, end, exclude)
+	if err != nil {
+		return err
+	}
+	if domain.PeakOverlap(busy, start, end) >= fleet {
+		return domain.ErrSlotFull
+	}
+	return nil
+}
+
+// mapBookingErr turns constraint violations on bookings into domain errors.
+func mapBookingErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "bookings_duration_ride_type_chk" {
+		return domain.ErrInvalidDuration
+	}
+	return err
 }
 
 var _ BookingRepo = (*BookingRepoImpl)(nil)
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobRepo interface {
+	// TryLock takes the cluster-wide advisory lock for job, without waiting.
+	// ok is false if another instance holds it. The lock is held on a
+	// dedicated connection until release is called, or the connection dies.
+	TryLock(ctx context.Context, job string) (release func(), ok bool, err error)
+	// LastStarted is when the job's latest run started, or the zero time.
+	LastStarted(ctx context.Context, job string) (time.Time, error)
+	StartRun(ctx context.Context, job, instance string) (int64, error)
+	FinishRun(ctx context.Context, id, affected int64, runErr error) error
+	// Latest returns the latest run of every job.
+	Latest(ctx context.Context) ([]domain.JobRun, error)
+	// Purge deletes runs that started more than olderThan ago, except the
+	// latest of each job, which decides when it is next due.
+	Purge(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+type JobRepoImpl struct{ pool *pgxpool.Pool }
+
+func NewJobRepo(pool *pgxpool.Pool) *JobRepoImpl { return &JobRepoImpl{pool: pool} }
+
+func (r *JobRepoImpl) TryLock(ctx context.Context, job string) (func(), bool, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	qctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var ok bool
+	if err := conn.QueryRow(qctx, `SELECT pg_try_advisory_lock(hashtextextended('job:' || $1, 0))`, job).Scan(&ok); err != nil || !ok {
+		conn.Release()
+		return nil, false, err
+	}
+	release := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(ctx, `SELECT pg_advisory_unlock(hashtextextended('job:' || $1, 0))`, job); err != nil {
+			// Closing the session is the only other way to drop the lock.
+			_ = conn.Conn().Close(ctx)
+		}
+		conn.Release()
+	}
+	return release, true, nil
+}
+
+func (r *JobRepoImpl) LastStarted(ctx context.Context, job string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	var t time.Time
+	err := r.pool.QueryRow(ctx, `SELECT started_at FROM job_runs WHERE job=$1 ORDER BY started_at DESC LIMIT 1`, job).Scan(&t)
+	if err == pgx.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return t, err
+}
+
+func (r *JobRepoImpl) StartRun(ctx context.Context, job, instance string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	var id int64
+	err := r.pool.QueryRow(ctx, `INSERT INTO job_runs (job, instance) VALUES ($1,$2) RETURNING id`, job, instance).Scan(&id)
+	return id, err
+}
+
+func (r *JobRepoImpl) FinishRun(ctx context.Context, id, affected int64, runErr error) error {
+	msg := ""
+	if runErr != nil {
+		msg = runErr.Error()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.pool.Exec(ctx, `UPDATE job_runs SET finished_at=now(), affected=$2, error=$3 WHERE id=$1`, id, affected, msg)
+	return err
+}
+
+func (r *JobRepoImpl) Latest(ctx context.Context) ([]domain.JobRun, error) {
+	const q = `
+		SELECT DISTINCT ON (job) id, job, instance, started_at, finished_at, affected, error
+		FROM job_runs
+		ORDER BY job, started_at DESC`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.JobRun{}
+	for rows.Next() {
+		var j domain.JobRun
+		if err := rows.Scan(&j.ID, &j.Job, &j.Instance, &j.StartedAt, &j.FinishedAt, &j.Affected, &j.Error); err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+func (r *JobRepoImpl) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	const q = `
+		DELETE FROM job_runs
+		WHERE started_at < now() - $1::interval
+		  AND id NOT IN (SELECT DISTINCT ON (job) id FROM job_runs ORDER BY job, started_at DESC)`
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	tag, err := r.pool.Exec(ctx, q, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+var _ JobRepo = (*JobRepoImpl)(nil)
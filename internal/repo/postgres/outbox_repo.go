@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxRepo is the outbox of emails and texts. Messages are enqueued by the
+// request that causes them and delivered later by a worker.
+type OutboxRepo interface {
+	Enqueue(ctx context.Context, m *domain.OutboxMessage) error
+	// Claim takes up to limit pending messages that are due and pushes their
+	// next attempt out by lease, so other workers skip them while this one
+	// sends. Each claim counts as an attempt.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxMessage, error)
+	// Renew pushes a claimed message's lease out to lease from now, just before
+	// it is sent. It reports false if the message was claimed again since
+	// (attempts moved past the given count) or is no longer pending, in which
+	// case the caller must not send it.
+	Renew(ctx context.Context, id int64, attempts int, lease time.Duration) (bool, error)
+	// MarkSent records delivery and blanks the bodies, which may hold links
+	// and codes that must not outlive the message.
+	MarkSent(ctx context.Context, id int64) error
+	// MarkFailed records a failed attempt. The message is retried at next, or
+	// dead-lettered when dead is set, which blanks its bodies like MarkSent.
+	MarkFailed(ctx context.Context, id int64, errMsg string, next time.Time, dead bool) error
+	// Purge deletes sent and dead messages older than olderThan and reports
+	// how many it removed.
+	Purge(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+type OutboxRepoImpl struct{ pool *pgxpool.Pool }
+
+func NewOutboxRepo(pool *pgxpool.Pool) *OutboxRepoImpl { return &OutboxRepoImpl{pool: pool} }
+
+const outboxCols = `id, channel, to_email, to_name, to_phone, subject, text_body, html_body,
+status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+// outboxDest returns the scan destinations for outboxCols, in order.
+func outboxDest(m *domain.OutboxMessage) []any {
+	return []any{
+		&m.ID, &m.Channel, &m.ToEmail, &m.ToName, &m.ToPhone, &m.Subject, &m.Text, &m.HTML,
+		&m.Status, &m.Attempts, &m.NextAttemptAt, &m.LastError, &m.CreatedAt, &m.SentAt,
+	}
+}
+
+const insertOutbox = `
+	INSERT INTO email_outbox (channel, to_email, to_name, to_phone, subject, text_body, html_body)
+	VALUES (COALESCE(NULLIF($1,''),'email'),$2,$3,$4,$5,$6,$7)`
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// enqueueMessage inserts m through db, which may be the transaction of the change the message reports.
+func enqueueMessage(ctx context.Context, db execer, m domain.OutboxMessage) error {
+	_, err := db.Exec(ctx, insertOutbox, m.Channel, m.ToEmail, m.ToName, m.ToPhone, m.Subject, m.Text, m.HTML)
+	return err
+}
+
+func (r *OutboxRepoImpl) Enqueue(ctx context.Context, m *domain.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return r.pool.QueryRow(ctx, insertOutbox+` RETURNING `+outboxCols,
+		m.Channel, m.ToEmail, m.ToName, m.ToPhone, m.Subject, m.Text, m.HTML,
+	).Scan(outboxDest(m)...)
+}
+
+func (r *OutboxRepoImpl) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	const q = `
+		UPDATE email_outbox SET
+			attempts        = attempts + 1,
+			next_attempt_at = now() + $2::interval
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + outboxCols
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, q, limit, lease)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.OutboxMessage
+	for rows.Next() {
+		var m domain.OutboxMessage
+		if err := rows.Scan(outboxDest(&m)...); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *OutboxRepoImpl) Renew(ctx context.Context, id int64, attempts int, lease time.Duration) (bool, error) {
+	const q = `
+		UPDATE email_outbox SET next_attempt_at = now() + $3::interval
+		WHERE id=$1 AND attempts=$2 AND status='pending'`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	tag, err := r.pool.Exec(ctx, q, id, attempts, lease)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *OutboxRepoImpl) MarkSent(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.pool.Exec(ctx, `
+		UPDATE email_outbox SET status='sent', sent_at=now(), last_error='', text_body='', html_body=''
+		WHERE id=$1`, id)
+	return err
+}
+
+func (r *OutboxRepoImpl) MarkFailed(ctx context.Context, id int64, errMsg string, next time.Time, dead bool) error {
+	const q = `
+		UPDATE email_outbox SET
+			last_error      = $2,
+			next_attempt_at = $3,
+			status          = CASE WHEN $4 THEN 'dead' ELSE 'pending' END,
+			text_body       = CASE WHEN $4 THEN '' ELSE text_body END,
+			html_body       = CASE WHEN $4 THEN '' ELSE html_body END
+		WHERE id=$1`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.pool.Exec(ctx, q, id, errMsg, next, dead)
+	return err
+}
+
+func (r *OutboxRepoImpl) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	const q = `
+		DELETE FROM email_outbox
+		WHERE status IN ('sent', 'dead')
+		  AND COALESCE(sent_at, created_at) < now() - $1::interval`
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	tag, err := r.pool.Exec(ctx, q, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+var _ OutboxRepo = (*OutboxRepoImpl)(nil)
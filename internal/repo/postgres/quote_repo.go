@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// QuoteRepo stores priced quotes so a booking can later be created at the quoted fare.
+type QuoteRepo interface {
+	// Create assigns the quote an ID and stores it.
+	Create(ctx context.Context, q *domain.Quote) error
+	GetByID(ctx context.Context, id string) (*domain.Quote, error)
+}
+
+type QuoteRepoImpl struct{ pool *pgxpool.Pool }
+
+func NewQuoteRepo(pool *pgxpool.Pool) *QuoteRepoImpl { return &QuoteRepoImpl{pool: pool} }
+
+func (r *QuoteRepoImpl) Create(ctx context.Context, q *domain.Quote) error {
+	const sql = `
+		INSERT INTO quotes (id, ride_type, pickup, dropoff, scheduled_at, distance_miles, duration_minutes,
+		                    currency, items, total_cents, rate_card, expires_at, vehicle_class)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		RETURNING created_at`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	q.ID = uuid.NewString()
+	return r.pool.QueryRow(ctx, sql,
+		q.ID, q.RideType, q.Pickup, q.Dropoff, q.ScheduledAt, q.DistanceMiles, q.DurationMinutes,
+		q.Currency, q.Items, q.TotalCents, q.RateCard, q.ExpiresAt, q.VehicleClass,
+	).Scan(&q.CreatedAt)
+}
+
+func (r *QuoteRepoImpl) GetByID(ctx context.Context, id string) (*domain.Quote, error) {
+	const sql = `
+		SELECT id::text, ride_type, pickup, dropoff, scheduled_at, distance_miles::float8, duration_minutes,
+		       currency, items, total_cents, rate_card, booking_id, expires_at, created_at, vehicle_class
+		FROM quotes WHERE id=$1`
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var q domain.Quote
+	err := r.pool.QueryRow(ctx, sql, id).Scan(
+		&q.ID, &q.RideType, &q.Pickup, &q.Dropoff, &q.ScheduledAt, &q.DistanceMiles, &q.DurationMinutes,
+		&q.Currency, &q.Items, &q.TotalCents, &q.RateCard, &q.BookingID, &q.ExpiresAt, &q.CreatedAt, &q.VehicleClass,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+var _ QuoteRepo = (*QuoteRepoImpl)(nil)
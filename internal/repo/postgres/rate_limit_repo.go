@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateLimitRepo maintains the rate_limits table the rate limiting middleware
+// counts requests in.
+type RateLimitRepo interface {
+	// PurgeExpired deletes rows whose window has passed. Every limiter shares
+	// the table, so one periodic run covers them all.
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type RateLimitRepoImpl struct{ pool *pgxpool.Pool }
+
+func NewRateLimitRepo(pool *pgxpool.Pool) *RateLimitRepoImpl { return &RateLimitRepoImpl{pool: pool} }
+
+func (r *RateLimitRepoImpl) PurgeExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, `DELETE FROM rate_limits WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+var _ RateLimitRepo = (*RateLimitRepoImpl)(nil)
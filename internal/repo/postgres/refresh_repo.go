@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RefreshRepo stores hashed, rotating refresh tokens grouped into per-login families.
+type RefreshRepo interface {
+	// Create starts a new token family for a user's device.
+	Create(ctx context.Context, userID int64, tokenHash, device string, expiresAt time.Time) error
+	// Rotate marks the presented token used and stores its replacement in the same family,
+	// returning the owning user. Presenting an already used token revokes the family and
+	// returns domain.ErrRefreshTokenReused.
+	Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (userID int64, err error)
+	// RevokeFamily revokes every token in the family of the given token (logout).
+	RevokeFamily(ctx context.Context, tokenHash string) error
+	// RevokeAllForUser revokes every refresh token of a user, on all devices.
+	RevokeAllForUser(ctx context.Context, userID int64) error
+	// DeleteExpired removes tokens that expired or were revoked more than 30 days ago (maintenance).
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type RefreshRepoImpl struct{ pool *pgxpool.Pool }
+
+func NewRefreshRepo(pool *pgxpool.Pool) *RefreshRepoImpl { return &RefreshRepoImpl{pool: pool} }
+
+func (r *RefreshRepoImpl) Create(ctx context.Context, userID int64, tokenHash, device string, expiresAt time.Time) error {
+	const q = `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, device, expires_at)
+		VALUES ($1,$2,$3,$4,$5)`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.pool.Exec(ctx, q, userID, uuid.New(), tokenHash, device, expiresAt)
+	return err
+}
+
+func (r *RefreshRepoImpl) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		id, userID int64
+		family     uuid.UUID
+		device     string
+		expires    time.Time
+		used       *time.Time
+		revoked    *time.Time
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, family_id, device, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash=$1
+		FOR UPDATE`, oldHash).Scan(&id, &userID, &family, &device, &expires, &used, &revoked)
+	if err == pgx.ErrNoRows {
+		return 0, domain.ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	if revoked != nil || time.Now().After(expires) {
+		return 0, domain.ErrRefreshTokenInvalid
+	}
+	if used != nil {
+		// Someone is replaying a rotated token: kill every session of this family.
+		if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at=now() WHERE family_id=$1 AND revoked_at IS NULL`, family); err != nil {
+			return 0, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return 0, err
+		}
+		return 0, domain.ErrRefreshTokenReused
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET used_at=now() WHERE id=$1`, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, device, expires_at)
+		VALUES ($1,$2,$3,$4,$5)`, userID, family, newHash, device, expiresAt); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+func (r *RefreshRepoImpl) RevokeFamily(ctx context.Context, tokenHash string) error {
+	const q = `
+		UPDATE refresh_tokens SET revoked_at=now()
+		WHERE revoked_at IS NULL
+		  AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash=$1)`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.pool.Exec(ctx, q, tokenHash)
+	return err
+}
+
+func (r *RefreshRepoImpl) RevokeAllForUser(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return revokeRefreshTokens(ctx, r.pool, userID, "")
+}
+
+// revokeRefreshTokens revokes every live refresh token of a user through db,
+// so password changes can sign devices out in their own transaction. The
+// family of keepHash, if it is one of the user's tokens, stays signed in.
+func revokeRefreshTokens(ctx context.Context, db execer, userID int64, keepHash string) error {
+	const q = `
+		UPDATE refresh_tokens SET revoked_at=now()
+		WHERE user_id=$1 AND revoked_at IS NULL
+		  AND family_id IS DISTINCT FROM (SELECT family_id FROM refresh_tokens WHERE token_hash=$2 AND user_id=$1)`
+	_, err := db.Exec(ctx, q, userID, keepHash)
+	return err
+}
+
+func (r *RefreshRepoImpl) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM refresh_tokens
+		WHERE expires_at < now() - interval '30 days'
+		   OR revoked_at < now() - interval '30 days'`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+var _ RefreshRepo = (*RefreshRepoImpl)(nil)
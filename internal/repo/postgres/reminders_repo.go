@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReminderRepo interface {
+	// ClaimReminders records reminder kind as sent for up to limit bookings in
+	// domain.RemindableStatuses scheduled in (from, to] that have not had it
+	// yet, and returns them. The reminders, emails or texts, are rendered by
+	// messages and queued in the outbox in the same transaction, so a claimed
+	// reminder is always delivered, with the outbox's retries. A booking is
+	// only ever claimed once per kind and scheduled time, however many
+	// callers race for it.
+	ClaimReminders(ctx context.Context, kind string, from, to time.Time, limit int,
+		messages func(domain.Booking) ([]domain.OutboxMessage, error)) ([]domain.Booking, error)
+	// Purge deletes the records of reminders for trips scheduled more than
+	// olderThan ago. Past trips are never claimed again, so nothing is resent.
+	Purge(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+type ReminderRepoImpl struct{ pool *pgxpool.Pool }
+
+func NewReminderRepo(pool *pgxpool.Pool) *ReminderRepoImpl { return &ReminderRepoImpl{pool: pool} }
+
+func (r *ReminderRepoImpl) ClaimReminders(ctx context.Context, kind string, from, to time.Time, limit int,
+	messages func(domain.Booking) ([]domain.OutboxMessage, error)) ([]domain.Booking, error) {
+	const q = `
+		WITH due AS (
+			SELECT b.id, b.scheduled_at, b.reminder_channel
+			FROM bookings b
+			WHERE b.status::text = ANY($2::text[]) AND b.scheduled_at > $3 AND b.scheduled_at <= $4
+			  AND NOT EXISTS (
+				SELECT 1 FROM booking_reminders r
+				WHERE r.booking_id = b.id AND r.kind = $1 AND r.scheduled_at = b.scheduled_at
+			  )
+			ORDER BY b.scheduled_at
+			LIMIT $5
+		), claimed AS (
+			INSERT INTO booking_reminders (booking_id, kind, scheduled_at, channel)
+			SELECT id, $1, scheduled_at, reminder_channel FROM due
+			ON CONFLICT DO NOTHING
+			RETURNING booking_id
+		)
+		SELECT ` + bookingCols + ` FROM bookings WHERE id IN (SELECT booking_id FROM claimed)
+		ORDER BY scheduled_at`
+
+	statuses := make([]string, 0, len(domain.RemindableStatuses))
+	for _, st := range domain.RemindableStatuses {
+		statuses = append(statuses, string(st))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, q, kind, statuses, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []domain.Booking
+	for rows.Next() {
+		var b domain.Booking
+		if err := rows.Scan(bookingDest(&b)...); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range out {
+		msgs, err := messages(b)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			if err := enqueueMessage(ctx, tx, m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *ReminderRepoImpl) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	tag, err := r.pool.Exec(ctx, `DELETE FROM booking_reminders WHERE scheduled_at < now() - $1::interval`, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+var _ ReminderRepo = (*ReminderRepoImpl)(nil)
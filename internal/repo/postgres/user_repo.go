@@ -2,8 +2,11 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,31 +17,38 @@ type User struct {
 	PasswordHash string
 	Name         string
 	Phone        string
+	Locale       string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
 
 type UsersRepo interface {
-	Create(ctx context.Context, email, hash, name, phone string) (*User, error)
+	Create(ctx context.Context, email, hash, name, phone, locale string) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByID(ctx context.Context, id int64) (*User, error)
 	LinkExistingBookings(ctx context.Context, userID int64, email string) error
+	// UpdatePassword sets a new password hash and, in the same transaction,
+	// revokes the user's refresh tokens except the family of keepRefreshHash.
+	UpdatePassword(ctx context.Context, userID int64, hash, keepRefreshHash string) error
+	UpdateProfile(ctx context.Context, userID int64, name, phone, locale string) (*User, error)
+	// UpdateEmail swaps a user's address; it returns domain.ErrEmailTaken if another account has it.
+	UpdateEmail(ctx context.Context, userID int64, email string) error
 }
 
 type UsersRepoImpl struct{ pool *pgxpool.Pool }
 
 func NewUsersRepo(pool *pgxpool.Pool) *UsersRepoImpl { return &UsersRepoImpl{pool: pool} }
 
-func (r *UsersRepoImpl) Create(ctx context.Context, email, hash, name, phone string) (*User, error) {
+func (r *UsersRepoImpl) Create(ctx context.Context, email, hash, name, phone, locale string) (*User, error) {
 	const q = `
-INSERT INTO users (email, password_hash, name, phone)
-VALUES ($1,$2,$3,$4)
-RETURNING id, role, email, password_hash, name, phone, created_at, updated_at`
+INSERT INTO users (email, password_hash, name, phone, locale)
+VALUES ($1,$2,$3,$4,$5)
+RETURNING id, role, email, password_hash, name, phone, locale, created_at, updated_at`
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	var u User
-	if err := r.pool.QueryRow(ctx, q, email, hash, name, phone).Scan(
-		&u.ID, &u.Role, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.CreatedAt, &u.UpdatedAt,
+	if err := r.pool.QueryRow(ctx, q, email, hash, name, phone, locale).Scan(
+		&u.ID, &u.Role, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.Locale, &u.CreatedAt, &u.UpdatedAt,
 	); err != nil {
 		return nil, err
 	}
@@ -46,12 +56,12 @@ RETURNING id, role, email, password_hash, name, phone, created_at, updated_at`
 }
 
 func (r *UsersRepoImpl) FindByEmail(ctx context.Context, email string) (*User, error) {
-	const q = `SELECT id, role, email, password_hash, name, phone, created_at, updated_at FROM users WHERE email=$1`
+	const q = `SELECT id, role, email, password_hash, name, phone, locale, created_at, updated_at FROM users WHERE email=$1`
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	var u User
 	if err := r.pool.QueryRow(ctx, q, email).Scan(
-		&u.ID, &u.Role, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.CreatedAt, &u.UpdatedAt,
+		&u.ID, &u.Role, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.Locale, &u.CreatedAt, &u.UpdatedAt,
 	); err != nil {
 		return nil, err
 	}
@@ -59,12 +69,12 @@ func (r *UsersRepoImpl) FindByEmail(ctx context.Context, email string) (*User, e
 }
 
 func (r *UsersRepoImpl) FindByID(ctx context.Context, id int64) (*User, error) {
-	const q = `SELECT id, role, email, password_hash, name, phone, created_at, updated_at FROM users WHERE id=$1`
+	const q = `SELECT id, role, email, password_hash, name, phone, locale, created_at, updated_at FROM users WHERE id=$1`
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	var u User
 	if err := r.pool.QueryRow(ctx, q, id).Scan(
-		&u.ID, &u.Role, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.CreatedAt, &u.UpdatedAt,
+		&u.ID, &u.Role, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.Locale, &u.CreatedAt, &u.UpdatedAt,
 	); err != nil {
 		return nil, err
 	}
@@ -78,3 +88,53 @@ func (r *UsersRepoImpl) LinkExistingBookings(ctx context.Context, userID int64,
 	_, err := r.pool.Exec(ctx, q, userID, email)
 	return err
 }
+
+func (r *UsersRepoImpl) UpdatePassword(ctx context.Context, userID int64, hash, keepRefreshHash string) error {
+	const q = `UPDATE users SET password_hash=$2, updated_at=now() WHERE id=$1`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, q, userID, hash); err != nil {
+		return err
+	}
+	if err := revokeRefreshTokens(ctx, tx, userID, keepRefreshHash); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *UsersRepoImpl) UpdateProfile(ctx context.Context, userID int64, name, phone, locale string) (*User, error) {
+	const q = `
+UPDATE users SET name=$2, phone=$3, locale=$4, updated_at=now()
+WHERE id=$1
+RETURNING id, role, email, password_hash, name, phone, locale, created_at, updated_at`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	var u User
+	if err := r.pool.QueryRow(ctx, q, userID, name, phone, locale).Scan(
+		&u.ID, &u.Role, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.Locale, &u.CreatedAt, &u.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *UsersRepoImpl) UpdateEmail(ctx context.Context, userID int64, email string) error {
+	const q = `UPDATE users SET email=$2, updated_at=now() WHERE id=$1`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.pool.Exec(ctx, q, userID, email)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return domain.ErrEmailTaken
+	}
+	return err
+}
+
+var _ UsersRepo = (*UsersRepoImpl)(nil)
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// VehicleRepo is the fleet inventory. Create and Update return domain.ErrPlateTaken on a duplicate plate.
+type VehicleRepo interface {
+	Create(ctx context.Context, v *domain.Vehicle) (*domain.Vehicle, error)
+	GetByID(ctx context.Context, id int64) (*domain.Vehicle, error)
+	List(ctx context.Context, class string, activeOnly bool, limit, offset int) ([]domain.Vehicle, error)
+	Update(ctx context.Context, id int64, p domain.VehiclePatch) (*domain.Vehicle, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+	// CheckCapacity returns domain.ErrUnknownVehicleClass if the class has no active
+	// vehicle, or a *domain.CapacityError if no active vehicle of the class fits the party.
+	CheckCapacity(ctx context.Context, class string, passengers, luggages int) error
+	// ActiveCounts returns the number of active vehicles per class.
+	ActiveCounts(ctx context.Context) (map[string]int, error)
+}
+
+type VehicleRepoImpl struct{ pool *pgxpool.Pool }
+
+func NewVehicleRepo(pool *pgxpool.Pool) *VehicleRepoImpl { return &VehicleRepoImpl{pool: pool} }
+
+const vehicleCols = `id, class, make_model, plate, capacity, luggage_capacity, active, created_at, updated_at`
+
+func vehicleDest(v *domain.Vehicle) []any {
+	return []any{&v.ID, &v.Class, &v.MakeModel, &v.Plate, &v.Capacity, &v.LuggageCapacity, &v.Active, &v.CreatedAt, &v.UpdatedAt}
+}
+
+func (r *VehicleRepoImpl) Create(ctx context.Context, in *domain.Vehicle) (*domain.Vehicle, error) {
+	const q = `
+		INSERT INTO vehicles (class, make_model, plate, capacity, luggage_capacity, active)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING ` + vehicleCols
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var v domain.Vehicle
+	err := r.pool.QueryRow(ctx, q, in.Class, in.MakeModel, in.Plate, in.Capacity, in.LuggageCapacity, in.Active).
+		Scan(vehicleDest(&v)...)
+	if err != nil {
+		return nil, mapVehicleErr(err)
+	}
+	return &v, nil
+}
+
+func (r *VehicleRepoImpl) GetByID(ctx context.Context, id int64) (*domain.Vehicle, error) {
+	const q = `SELECT ` + vehicleCols + ` FROM vehicles WHERE id=$1`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var v domain.Vehicle
+	err := r.pool.QueryRow(ctx, q, id).Scan(vehicleDest(&v)...)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *VehicleRepoImpl) List(ctx context.Context, class string, activeOnly bool, limit, offset int) ([]domain.Vehicle, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	const q = `
+		SELECT ` + vehicleCols + ` FROM vehicles
+		WHERE ($1 = '' OR class = $1) AND (NOT $2 OR active)
+		ORDER BY class, id
+		LIMIT $3 OFFSET $4`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, q, class, activeOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.Vehicle
+	for rows.Next() {
+		var v domain.Vehicle
+		if err := rows.Scan(vehicleDest(&v)...); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func (r *VehicleRepoImpl) Update(ctx context.Context, id int64, p domain.VehiclePatch) (*domain.Vehicle, error) {
+	const q = `
+		UPDATE vehicles SET
+			class            = COALESCE($2, class),
+			make_model       = COALESCE($3, make_model),
+			plate            = COALESCE($4, plate),
+			capacity         = COALESCE($5, capacity),
+			luggage_capacity = COALESCE($6, luggage_capacity),
+			active           = COALESCE($7, active),
+			updated_at       = now()
+		WHERE id=$1
+		RETURNING ` + vehicleCols
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var v domain.Vehicle
+	err := r.pool.QueryRow(ctx, q, id, p.Class, p.MakeModel, p.Plate, p.Capacity, p.LuggageCapacity, p.Active).
+		Scan(vehicleDest(&v)...)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, mapVehicleErr(err)
+	}
+	return &v, nil
+}
+
+func (r *VehicleRepoImpl) Delete(ctx context.Context, id int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	tag, err := r.pool.Exec(ctx, `DELETE FROM vehicles WHERE id=$1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *VehicleRepoImpl) CheckCapacity(ctx context.Context, class string, passengers, luggages int) error {
+	const q = `
+		SELECT COUNT(*),
+		       COALESCE(MAX(capacity), 0),
+		       COALESCE(MAX(luggage_capacity), 0),
+		       EXISTS (SELECT 1 FROM vehicles
+		               WHERE class=$1 AND active AND capacity >= $2 AND luggage_capacity >= $3)
+		FROM vehicles WHERE class=$1 AND active`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var (
+		count            int
+		maxSeats, maxBag int
+		fits             bool
+	)
+	if err := r.pool.QueryRow(ctx, q, class, passengers, luggages).Scan(&count, &maxSeats, &maxBag, &fits); err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain.ErrUnknownVehicleClass
+	}
+	if !fits {
+		return &domain.CapacityError{Class: class, Capacity: maxSeats, LuggageCapacity: maxBag}
+	}
+	return nil
+}
+
+func (r *VehicleRepoImpl) ActiveCounts(ctx context.Context) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, `SELECT class, COUNT(*) FROM vehicles WHERE active GROUP BY class`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]int{}
+	for rows.Next() {
+		var (
+			class string
+			n     int
+		)
+		if err := rows.Scan(&class, &n); err != nil {
+			return nil, err
+		}
+		out[class] = n
+	}
+	return out, rows.Err()
+}
+
+func mapVehicleErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return domain.ErrPlateTaken
+	}
+	return err
+}
+
+var _ VehicleRepo = (*VehicleRepoImpl)(nil)
@@ -5,6 +5,8 @@ import (
 	"net"
 	"time"
 
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/platform/auth"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
@@ -12,8 +14,9 @@ import (
 
 // VerifyRepo defines operations for email verification tokens and user verification flags.
 type VerifyRepo interface {
-	// CreateEmailVerification inserts a one-time verification token for a user.
-	CreateEmailVerification(ctx context.Context, userID int64, token string, expiresAt time.Time) error
+	// CreateEmailVerification inserts a one-time verification token for a user
+	// and queues mail, the email carrying it, in the same transaction.
+	CreateEmailVerification(ctx context.Context, userID int64, token string, expiresAt time.Time, mail domain.OutboxMessage) error
 	// ConsumeEmailVerification marks a token used if valid, and returns the userID (0 if not found/invalid/expired/used).
 	ConsumeEmailVerification(ctx context.Context, token string) (userID int64, err error)
 	// MarkUserVerified sets users.is_verified = true.
@@ -25,28 +28,63 @@ type VerifyRepo interface {
 	// DeleteExpiredTokens removes old/expired tokens (maintenance).
 	DeleteExpiredTokens(ctx context.Context) (int64, error)
 
+	// password reset:
+	// CreatePasswordReset stores a single-use reset token (hashed) for a user and queues mail.
+	CreatePasswordReset(ctx context.Context, userID int64, token string, expiresAt time.Time, mail domain.OutboxMessage) error
+	// ResetPassword consumes a valid reset token, sets the user's password hash and revokes
+	// their refresh tokens in one transaction, returning the userID (0 if
+	// not found/invalid/expired/used).
+	ResetPassword(ctx context.Context, token, passwordHash string) (userID int64, err error)
+
+	// email change:
+	// CreateEmailChange stores a single-use token (hashed) confirming a move to newEmail
+	// and queues mail.
+	CreateEmailChange(ctx context.Context, userID int64, newEmail, token string, expiresAt time.Time, mail domain.OutboxMessage) error
+	// ConsumeEmailChange marks a change token used if valid, and returns the userID and the
+	// confirmed address (0 and "" if not found/invalid/expired/used).
+	ConsumeEmailChange(ctx context.Context, token string) (userID int64, newEmail string, err error)
+
 	// guest access:
-	CreateGuestAccess(ctx context.Context, email, codeHash, magic string, expiresAt time.Time, ip net.IP) error
+	// CreateGuestAccess stores a guest access code and magic link and queues
+	// msg, the email or text carrying them, in the same transaction.
+	CreateGuestAccess(ctx context.Context, email, codeHash, magic string, expiresAt time.Time, ip net.IP, msg domain.OutboxMessage) error
 	CheckGuestCode(ctx context.Context, email, code string) (bool, error)
 	ConsumeGuestMagic(ctx context.Context, token string) (string, bool, error)
+	// AddGuestPhone records phone as verified for the guest email. Only call it
+	// for a phone given by someone who has proven they own the email.
+	AddGuestPhone(ctx context.Context, email, phone string) error
+	// GuestPhone returns the phone most recently verified for email, or "".
+	GuestPhone(ctx context.Context, email string) (string, error)
 }
 
 type VerifyRepoImpl struct{ pool *pgxpool.Pool }
 
 func NewVerifyRepo(pool *pgxpool.Pool) *VerifyRepoImpl { return &VerifyRepoImpl{pool: pool} }
 
-func (r *VerifyRepoImpl) CreateEmailVerification(ctx context.Context, userID int64, token string, expiresAt time.Time) error {
+func (r *VerifyRepoImpl) CreateEmailVerification(ctx context.Context, userID int64, token string, expiresAt time.Time, mail domain.OutboxMessage) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	// You can keep multiple tokens per user; the most recent is usually sent in email.
 	// If you prefer only one active at a time, add: DELETE FROM ... WHERE user_id=$1 AND used_at IS NULL
-	_, err := r.pool.Exec(ctx,
+	_, err = tx.Exec(ctx,
 		`INSERT INTO email_verification_tokens (user_id, token, expires_at)
          VALUES ($1, $2, $3)`,
 		userID, token, expiresAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := enqueueMessage(ctx, tx, mail); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *VerifyRepoImpl) ConsumeEmailVerification(ctx context.Context, token string) (int64, error) {
@@ -110,17 +148,172 @@ WHERE (used_at IS NOT NULL AND used_at < now() - interval '30 days')
 	if err != nil {
 		return 0, err
 	}
-	return tag.RowsAffected(), nil
+	resets, err := r.pool.Exec(ctx, `
+DELETE FROM password_reset_tokens
+WHERE (used_at IS NOT NULL AND used_at < now() - interval '30 days')
+   OR (used_at IS NULL AND expires_at < now() - interval '30 days')
+`)
+	if err != nil {
+		return 0, err
+	}
+	changes, err := r.pool.Exec(ctx, `
+DELETE FROM email_change_tokens
+WHERE (used_at IS NOT NULL AND used_at < now() - interval '30 days')
+   OR (used_at IS NULL AND expires_at < now() - interval '30 days')
+`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected() + resets.RowsAffected() + changes.RowsAffected(), nil
 }
-func (r *VerifyRepoImpl) CreateGuestAccess(ctx context.Context, email, codeHash, magic string, expiresAt time.Time, ip net.IP) error {
+
+func (r *VerifyRepoImpl) CreatePasswordReset(ctx context.Context, userID int64, token string, expiresAt time.Time, mail domain.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Only the newest link should work: retire any outstanding ones first.
+	_, err = tx.Exec(ctx, `
+UPDATE password_reset_tokens SET used_at = now()
+WHERE user_id = $1 AND used_at IS NULL
+`, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+         VALUES ($1, $2, $3)`,
+		userID, auth.HashToken(token), expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	if err := enqueueMessage(ctx, tx, mail); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *VerifyRepoImpl) ResetPassword(ctx context.Context, token, passwordHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The token is only spent if the password change commits with it.
+	var userID int64
+	err = tx.QueryRow(ctx, `
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING user_id
+`, auth.HashToken(token)).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, nil // invalid, used or expired
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET password_hash=$2, updated_at=now() WHERE id=$1`, userID, passwordHash); err != nil {
+		return 0, err
+	}
+	// Sign out every device that logged in with the old password.
+	if err := revokeRefreshTokens(ctx, tx, userID, ""); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (r *VerifyRepoImpl) CreateEmailChange(ctx context.Context, userID int64, newEmail, token string, expiresAt time.Time, mail domain.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// A new request supersedes any pending change for the same user.
+	_, err = tx.Exec(ctx, `
+UPDATE email_change_tokens SET used_at = now()
+WHERE user_id = $1 AND used_at IS NULL
+`, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO email_change_tokens (user_id, new_email, token_hash, expires_at)
+         VALUES ($1, $2, $3, $4)`,
+		userID, newEmail, auth.HashToken(token), expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	if err := enqueueMessage(ctx, tx, mail); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *VerifyRepoImpl) ConsumeEmailChange(ctx context.Context, token string) (int64, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var (
+		userID   int64
+		newEmail string
+	)
+	err := r.pool.QueryRow(ctx, `
+UPDATE email_change_tokens
+SET used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING user_id, new_email::text
+`, auth.HashToken(token)).Scan(&userID, &newEmail)
+
+	if err == pgx.ErrNoRows {
+		return 0, "", nil // invalid, used or expired
+	}
+	return userID, newEmail, err
+}
+
+func (r *VerifyRepoImpl) CreateGuestAccess(ctx context.Context, email, codeHash, magic string, expiresAt time.Time, ip net.IP, msg domain.OutboxMessage) error {
 	const q = `
 		INSERT INTO guest_access_codes(email, code_hash, token, expires_at, ip_created)
 		VALUES($1,$2,$3,$4,$5)
 	`
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	_, err := r.pool.Exec(ctx, q, email, codeHash, magic, expiresAt, ip)
-	return err
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, q, email, codeHash, magic, expiresAt, ip); err != nil {
+		return err
+	}
+	if err := enqueueMessage(ctx, tx, msg); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *VerifyRepoImpl) CheckGuestCode(ctx context.Context, email, code string) (bool, error) {
@@ -187,3 +380,32 @@ func (r *VerifyRepoImpl) ConsumeGuestMagic(ctx context.Context, token string) (s
 	_, _ = r.pool.Exec(ctx, `UPDATE guest_access_codes SET used_at=now() WHERE id=$1`, id)
 	return email, true, nil
 }
+
+func (r *VerifyRepoImpl) AddGuestPhone(ctx context.Context, email, phone string) error {
+	const q = `
+		INSERT INTO guest_phones (email, phone) VALUES ($1, $2)
+		ON CONFLICT (email, phone) DO UPDATE SET verified_at = now()
+	`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := r.pool.Exec(ctx, q, email, phone)
+	return err
+}
+
+func (r *VerifyRepoImpl) GuestPhone(ctx context.Context, email string) (string, error) {
+	const q = `
+		SELECT phone FROM guest_phones
+		WHERE email = $1
+		ORDER BY verified_at DESC
+		LIMIT 1
+	`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var phone string
+	err := r.pool.QueryRow(ctx, q, email).Scan(&phone)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return phone, err
+}
@@ -0,0 +1,229 @@
+// Package templates renders the emails and text messages the API sends. Each
+// email is a pair of embedded files per locale: mail/<locale>/<name>.txt
+// defines the "subject" and "text" templates, mail/<locale>/<name>.html
+// defines "html". Files whose name starts with an underscore are partials
+// shared by every email of their locale. A text message is a single file,
+// sms/<locale>/<name>.txt. Everything is parsed once, at startup.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed all:mail sms
+var files embed.FS
+
+// DefaultLocale is used when the recipient's locale is unknown or unsupported,
+// and for emails not translated into the recipient's locale.
+const DefaultLocale = "en"
+
+// dateLayouts is how each locale writes a date and time; locales not listed use DefaultLocale's.
+var dateLayouts = map[string]string{
+	"en": "Mon Jan 2, 2006 at 15:04 MST",
+	"es": "02/01/2006 a las 15:04 MST",
+}
+
+// Email is a rendered email.
+type Email struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// LinkData is the data of emails that carry a single link: account
+// verification, password reset and email change.
+type LinkData struct {
+	Name string
+	URL  string
+	// Hours is how long the link stays valid.
+	Hours int
+}
+
+// GuestAccessData is the data of the guest access email.
+type GuestAccessData struct {
+	Code string
+	Link string
+}
+
+type email struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// emails and texts are keyed by locale, then name.
+var (
+	emails = must(load(files))
+	texts  = must(loadSMS(files))
+)
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func load(fsys fs.FS) (map[string]map[string]email, error) {
+	locales, err := fs.ReadDir(fsys, "mail")
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]map[string]email{}
+	for _, l := range locales {
+		if !l.IsDir() {
+			continue
+		}
+		loc := l.Name()
+		dir := path.Join("mail", loc)
+		txtPartials, err := fs.Glob(fsys, path.Join(dir, "_*.txt"))
+		if err != nil {
+			return nil, err
+		}
+		htmlPartials, err := fs.Glob(fsys, path.Join(dir, "_*.html"))
+		if err != nil {
+			return nil, err
+		}
+		names, err := fs.Glob(fsys, path.Join(dir, "*.txt"))
+		if err != nil {
+			return nil, err
+		}
+
+		out[loc] = map[string]email{}
+		for _, file := range names {
+			name := strings.TrimSuffix(path.Base(file), ".txt")
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+			text, err := texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs(loc))).
+				ParseFS(fsys, append(slices.Clip(txtPartials), file)...)
+			if err != nil {
+				return nil, err
+			}
+			html, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs(loc))).
+				ParseFS(fsys, append(slices.Clip(htmlPartials), path.Join(dir, name+".html"))...)
+			if err != nil {
+				return nil, fmt.Errorf("templates: %s/%s: %w", loc, name, err)
+			}
+			for _, t := range []string{"subject", "text"} {
+				if text.Lookup(t) == nil {
+					return nil, fmt.Errorf("templates: %s: no %q template", file, t)
+				}
+			}
+			if html.Lookup("html") == nil {
+				return nil, fmt.Errorf("templates: %s/%s.html: no \"html\" template", loc, name)
+			}
+			out[loc][name] = email{text: text, html: html}
+		}
+	}
+	if _, ok := out[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("templates: default locale %q missing", DefaultLocale)
+	}
+	return out, nil
+}
+
+func loadSMS(fsys fs.FS) (map[string]map[string]*texttemplate.Template, error) {
+	names, err := fs.Glob(fsys, "sms/*/*.txt")
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]map[string]*texttemplate.Template{}
+	for _, file := range names {
+		loc := path.Base(path.Dir(file))
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+		t, err := texttemplate.New(path.Base(file)).Funcs(texttemplate.FuncMap(funcs(loc))).ParseFS(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		if out[loc] == nil {
+			out[loc] = map[string]*texttemplate.Template{}
+		}
+		out[loc][name] = t
+	}
+	return out, nil
+}
+
+func funcs(locale string) map[string]any {
+	layout, ok := dateLayouts[locale]
+	if !ok {
+		layout = dateLayouts[DefaultLocale]
+	}
+	return map[string]any{
+		"datetime": func(t time.Time) string { return t.Format(layout) },
+	}
+}
+
+// Match returns the supported locale for a language tag such as "es",
+// "es-MX" or the first entry of an Accept-Language header.
+func Match(tag string) (string, bool) {
+	tag, _, _ = strings.Cut(tag, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if _, ok := emails[tag]; ok {
+		return tag, true
+	}
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if _, ok := emails[base]; ok {
+		return base, true
+	}
+	return "", false
+}
+
+// Locale is Match, falling back to DefaultLocale.
+func Locale(tag string) string {
+	if l, ok := Match(tag); ok {
+		return l
+	}
+	return DefaultLocale
+}
+
+// Render renders the named email in locale, or in DefaultLocale if it has
+// not been translated.
+func Render(locale, name string, data any) (Email, error) {
+	e, ok := emails[Locale(locale)][name]
+	if !ok {
+		if e, ok = emails[DefaultLocale][name]; !ok {
+			return Email{}, fmt.Errorf("templates: unknown email %q", name)
+		}
+	}
+
+	var s, t, h bytes.Buffer
+	if err := e.text.ExecuteTemplate(&s, "subject", data); err != nil {
+		return Email{}, err
+	}
+	if err := e.text.ExecuteTemplate(&t, "text", data); err != nil {
+		return Email{}, err
+	}
+	if err := e.html.ExecuteTemplate(&h, "html", data); err != nil {
+		return Email{}, err
+	}
+	return Email{
+		Subject: strings.TrimSpace(s.String()),
+		Text:    strings.TrimSpace(t.String()) + "\n",
+		HTML:    strings.TrimSpace(h.String()),
+	}, nil
+}
+
+// RenderSMS renders the named text message in locale, or in DefaultLocale if
+// it has not been translated.
+func RenderSMS(locale, name string, data any) (string, error) {
+	t, ok := texts[Locale(locale)][name]
+	if !ok {
+		if t, ok = texts[DefaultLocale][name]; !ok {
+			return "", fmt.Errorf("templates: unknown text message %q", name)
+		}
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,101 @@
+package templates_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diagnosis/luxsuv-bookings/internal/domain"
+	"github.com/diagnosis/luxsuv-bookings/internal/templates"
+)
+
+func TestRenderLocales(t *testing.T) {
+	booking := struct {
+		Booking    domain.Booking
+		ManageURL  string
+		DriverName string
+	}{
+		Booking:    domain.Booking{ID: 42, RiderName: "Ana <script>", ScheduledAt: time.Date(2030, 3, 12, 12, 0, 0, 0, time.UTC)},
+		ManageURL:  "https://app.example.com/bookings/42/manage?manage_token=tok",
+		DriverName: "Sam",
+	}
+	link := templates.LinkData{Name: "Ana <script>", URL: "https://app.example.com/verify-email?token=tok", Hours: 2}
+
+	cases := map[string]any{
+		"booking_created":   booking,
+		"booking_updated":   booking,
+		"booking_confirmed": booking,
+		"booking_assigned":  booking,
+		"booking_canceled":  booking,
+		"driver_assigned":   booking,
+		"verify_email":      link,
+		"password_reset":    link,
+		"email_change":      link,
+		"trip_reminder":     booking,
+		"guest_access":      templates.GuestAccessData{Code: "123456", Link: "https://app.example.com/guest"},
+	}
+	for _, locale := range []string{"en", "es"} {
+		for name, data := range cases {
+			e, err := templates.Render(locale, name, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, name, err)
+			}
+			if !strings.Contains(e.Subject, "LuxSuv") || strings.Contains(e.Subject, "\n") {
+				t.Errorf("%s/%s: subject %q", locale, name, e.Subject)
+			}
+			if e.Text == "" || e.HTML == "" {
+				t.Errorf("%s/%s: empty body", locale, name)
+			}
+			if strings.Contains(e.HTML, "<script>") {
+				t.Errorf("%s/%s: data not escaped in html", locale, name)
+			}
+		}
+	}
+}
+
+func TestRenderPicksLocale(t *testing.T) {
+	data := templates.GuestAccessData{Code: "123456", Link: "https://app.example.com/guest"}
+	for tag, want := range map[string]string{
+		"es":             "código",
+		"es-MX":          "código",
+		"es-ES,en;q=0.8": "código",
+		"fr":             "access code",
+		"":               "access code",
+	} {
+		e, err := templates.Render(tag, "guest_access", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(e.Text, want) {
+			t.Errorf("Render(%q): text %q, want it to contain %q", tag, e.Text, want)
+		}
+	}
+
+	if _, err := templates.Render("en", "no_such_email", nil); err == nil {
+		t.Error("unknown email rendered")
+	}
+}
+
+func TestRenderSMS(t *testing.T) {
+	booking := struct {
+		Booking   domain.Booking
+		ManageURL string
+	}{
+		Booking:   domain.Booking{Pickup: "SFO", Dropoff: "Union Square", ScheduledAt: time.Date(2030, 3, 12, 12, 0, 0, 0, time.UTC)},
+		ManageURL: "https://app.example.com/bookings/42/manage?manage_token=tok",
+	}
+	for _, locale := range []string{"en", "es"} {
+		for name, data := range map[string]any{
+			"guest_access":  templates.GuestAccessData{Code: "123456", Link: "https://app.example.com/guest"},
+			"trip_reminder": booking,
+		} {
+			body, err := templates.RenderSMS(locale, name, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, name, err)
+			}
+			if !strings.Contains(body, "LuxSuv") || strings.Contains(body, "\n") {
+				t.Errorf("%s/%s: body %q", locale, name, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Drivers are users with role 'driver'; tie bookings.driver_id to them.
+
+-- 1) Clear assignments pointing at users that no longer exist
+UPDATE bookings
+SET driver_id = NULL
+WHERE driver_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = bookings.driver_id);
+
+-- 2) Foreign key (re-runnable)
+DO $$
+BEGIN
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_driver_id_fk
+        FOREIGN KEY (driver_id) REFERENCES users(id) ON DELETE SET NULL;
+EXCEPTION
+    WHEN duplicate_object THEN
+        NULL;
+END $$;
+
+-- 3) Lookups of a driver's rides around a time
+CREATE INDEX IF NOT EXISTS bookings_driver_id_scheduled_at_idx
+    ON bookings (driver_id, scheduled_at)
+    WHERE driver_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS bookings_driver_id_scheduled_at_idx;
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_driver_id_fk;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS started_at   TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS started_at;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rotating refresh tokens. Each login starts a family; every refresh marks the
+-- presented token used and issues a new one in the same family. Presenting a
+-- used token again revokes the whole family.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id          BIGSERIAL   PRIMARY KEY,
+    user_id     BIGINT      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id   UUID        NOT NULL,
+    token_hash  TEXT        NOT NULL UNIQUE,          -- SHA256 of the raw token
+    device      TEXT        NOT NULL DEFAULT '',
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    revoked_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_expires_at_idx ON refresh_tokens (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS refresh_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS quotes (
+    id               UUID        PRIMARY KEY,
+    ride_type        TEXT        NOT NULL,
+    pickup           TEXT        NOT NULL,
+    dropoff          TEXT        NOT NULL,
+    scheduled_at     TIMESTAMPTZ NOT NULL,
+    distance_miles   NUMERIC(8,2) NOT NULL DEFAULT 0,
+    duration_minutes INT         NOT NULL DEFAULT 0,
+    currency         TEXT        NOT NULL,
+    items            JSONB       NOT NULL,
+    total_cents      BIGINT      NOT NULL,
+    rate_card        TEXT        NOT NULL,
+    booking_id       BIGINT      UNIQUE REFERENCES bookings(id) ON DELETE SET NULL,
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS quote_id   UUID   NULL REFERENCES quotes(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS fare_cents BIGINT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS fare_cents,
+    DROP COLUMN IF EXISTS quote_id;
+DROP TABLE IF EXISTS quotes;
+-- +goose StatementEnd
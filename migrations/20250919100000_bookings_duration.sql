@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS duration_minutes INT NULL;
+
+-- 1) Hourly bookings made before durations existed get the default
+--    two-hour block (the rate card's minimum); per-ride ones have none.
+UPDATE bookings SET duration_minutes = 120
+WHERE ride_type = 'hourly' AND duration_minutes IS NULL;
+UPDATE bookings SET duration_minutes = NULL
+WHERE ride_type <> 'hourly' AND duration_minutes IS NOT NULL;
+
+-- 2) Hourly bookings need a duration, per-ride bookings must not have one (re-runnable)
+DO $$
+BEGIN
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_duration_ride_type_chk
+    CHECK ((ride_type = 'hourly') = (duration_minutes IS NOT NULL)) NOT VALID;
+EXCEPTION
+    WHEN duplicate_object THEN
+        NULL;
+END $$;
+
+-- 3) Every row now satisfies it; validating takes a lighter lock than adding it valid.
+ALTER TABLE bookings VALIDATE CONSTRAINT bookings_duration_ride_type_chk;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_duration_ride_type_chk;
+ALTER TABLE bookings DROP COLUMN IF EXISTS duration_minutes;
+-- +goose StatementEnd
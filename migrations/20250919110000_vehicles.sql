@@ -0,0 +1,71 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS vehicles (
+    id               BIGSERIAL   PRIMARY KEY,
+    class            TEXT        NOT NULL,
+    make_model       TEXT        NOT NULL DEFAULT '',
+    plate            TEXT        NOT NULL UNIQUE,
+    capacity         INT         NOT NULL CHECK (capacity > 0),
+    luggage_capacity INT         NOT NULL CHECK (luggage_capacity >= 0),
+    active           BOOLEAN     NOT NULL DEFAULT true,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS vehicles_class_active_idx ON vehicles (class) WHERE active;
+
+-- Bookings default to 'suv' and are reserved against the fleet, so an empty
+-- fleet would refuse every booking. Start with one SUV sized to the old
+-- passenger/luggage limits; operators replace it via /v1/admin/vehicles.
+INSERT INTO vehicles (class, make_model, plate, capacity, luggage_capacity)
+SELECT 'suv', 'Default SUV', 'DEFAULT-SUV-1', 8, 10
+WHERE NOT EXISTS (SELECT 1 FROM vehicles);
+
+-- Party size is now checked against the requested class, not fixed numbers.
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS vehicle_class TEXT NOT NULL DEFAULT 'suv';
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_passengers_range;
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_luggages_range;
+DO $$
+BEGIN
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_passengers_min CHECK (passengers >= 1);
+EXCEPTION
+    WHEN duplicate_object THEN
+        NULL;
+END $$;
+DO $$
+BEGIN
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_luggages_min CHECK (luggages >= 0);
+EXCEPTION
+    WHEN duplicate_object THEN
+        NULL;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_luggages_min;
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_passengers_min;
+DO $$
+BEGIN
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_passengers_range
+        CHECK (passengers >= 1 AND passengers <= 8) NOT VALID;
+EXCEPTION
+    WHEN duplicate_object THEN
+        NULL;
+END $$;
+DO $$
+BEGIN
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_luggages_range
+        CHECK (luggages >= 0 AND luggages <= 10) NOT VALID;
+EXCEPTION
+    WHEN duplicate_object THEN
+        NULL;
+END $$;
+ALTER TABLE bookings DROP COLUMN IF EXISTS vehicle_class;
+DROP TABLE IF EXISTS vehicles;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Geocoded pickup/dropoff: {"formatted_address", "lat", "lng", "place_id"}.
+-- NULL when the address could not be geocoded.
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS pickup_location  JSONB,
+    ADD COLUMN IF NOT EXISTS dropoff_location JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS dropoff_location,
+    DROP COLUMN IF EXISTS pickup_location;
+-- +goose StatementEnd
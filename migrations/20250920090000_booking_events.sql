@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS booking_events (
+    id            BIGSERIAL   PRIMARY KEY,
+    booking_id    BIGINT      NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    type          TEXT        NOT NULL,
+    from_status   TEXT        NULL,
+    to_status     TEXT        NULL,
+    changes       JSONB       NULL,
+    actor_role    TEXT        NOT NULL,
+    actor_user_id BIGINT      NULL REFERENCES users(id) ON DELETE SET NULL,
+    actor_email   TEXT        NOT NULL DEFAULT '',
+    request_id    TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS booking_events_booking_idx ON booking_events (booking_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS booking_events;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Language emails are written in, e.g. 'en' or 'es'.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'en';
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'en';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bookings DROP COLUMN IF EXISTS locale;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+-- +goose StatementEnd
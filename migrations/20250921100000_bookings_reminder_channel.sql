@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- How the rider wants trip reminders: 'email' or 'sms' (to rider_phone).
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS reminder_channel TEXT NOT NULL DEFAULT 'email'
+        CHECK (reminder_channel IN ('email', 'sms'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bookings DROP COLUMN IF EXISTS reminder_channel;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per reminder sent. The row is inserted before the reminder goes out,
+-- so replicas racing for the same reminder cannot both send it. scheduled_at is
+-- part of the key so a rescheduled trip is reminded again.
+CREATE TABLE IF NOT EXISTS booking_reminders (
+    booking_id   BIGINT      NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    kind         TEXT        NOT NULL,
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    channel      TEXT        NOT NULL,
+    sent_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (booking_id, kind, scheduled_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS booking_reminders;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- History of periodic background jobs. Jobs are serialized across instances
+-- with advisory locks; a job is due when its latest run started long enough ago.
+CREATE TABLE IF NOT EXISTS job_runs (
+    id          BIGSERIAL   PRIMARY KEY,
+    job         TEXT        NOT NULL,
+    instance    TEXT        NOT NULL,
+    started_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ NULL,
+    affected    BIGINT      NOT NULL DEFAULT 0,
+    error       TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS job_runs_job_started_idx ON job_runs (job, started_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS job_runs;
+-- +goose StatementEnd
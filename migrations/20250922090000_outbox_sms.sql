@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Texts are queued next to emails so they get the same retries.
+ALTER TABLE email_outbox
+    ADD COLUMN IF NOT EXISTS channel  TEXT NOT NULL DEFAULT 'email',
+    ADD COLUMN IF NOT EXISTS to_phone TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_outbox ALTER COLUMN to_email SET DEFAULT '';
+ALTER TABLE email_outbox ALTER COLUMN subject SET DEFAULT '';
+
+DO $$
+BEGIN
+ALTER TABLE email_outbox
+    ADD CONSTRAINT email_outbox_channel_chk
+    CHECK ((channel = 'email' AND to_email <> '') OR (channel = 'sms' AND to_phone <> ''));
+EXCEPTION
+    WHEN duplicate_object THEN
+        NULL;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM email_outbox WHERE channel = 'sms';
+ALTER TABLE email_outbox DROP CONSTRAINT IF EXISTS email_outbox_channel_chk;
+ALTER TABLE email_outbox ALTER COLUMN subject DROP DEFAULT;
+ALTER TABLE email_outbox ALTER COLUMN to_email DROP DEFAULT;
+ALTER TABLE email_outbox DROP COLUMN IF EXISTS to_phone;
+ALTER TABLE email_outbox DROP COLUMN IF EXISTS channel;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Phones a guest's access codes may be texted to: ones booked with while signed
+-- in as that email, so the owner of the address chose them, not anyone who
+-- merely knows it.
+CREATE TABLE IF NOT EXISTS guest_phones (
+    email       CITEXT      NOT NULL,
+    phone       TEXT        NOT NULL,
+    verified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (email, phone)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS guest_phones;
+-- +goose StatementEnd
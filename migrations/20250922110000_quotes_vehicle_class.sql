@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Quotes are priced per vehicle class; older ones were for the default SUV.
+ALTER TABLE quotes
+    ADD COLUMN IF NOT EXISTS vehicle_class TEXT NOT NULL DEFAULT 'suv';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE quotes DROP COLUMN IF EXISTS vehicle_class;
+-- +goose StatementEnd